@@ -0,0 +1,253 @@
+package sqlboiler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+	loggerUtils "github.com/hdget/utils/logger"
+	"github.com/pkg/errors"
+)
+
+type ReadPolicy int
+
+const (
+	ReadPolicyRoundRobin ReadPolicy = iota // 轮询
+	ReadPolicyWeighted                     // 按权重随机
+)
+
+// Cluster 一主多从的数据库集群, 写操作和事务使用主库, 读操作可以使用从库
+type Cluster struct {
+	primary  boil.Executor
+	replicas []*replica
+	policy   ReadPolicy
+	next     atomic.Uint64 // 轮询计数
+}
+
+type ClusterOption func(*Cluster)
+
+type replica struct {
+	executor boil.Executor
+	weight   int
+	healthy  atomic.Bool
+}
+
+type readYourWritesKey struct{}
+
+const (
+	DefaultDatabase = "" // 默认数据库名
+)
+
+var (
+	// clusters 已注册的数据库集群
+	clusters sync.Map
+	// fallbackCluster 默认数据库未注册时使用boil.GetDB()
+	fallbackCluster = &Cluster{}
+	// errClusters 未注册的数据库对应的错误集群, 避免重复创建sql.DB
+	errClusters sync.Map
+)
+
+// NewCluster 创建数据库集群, primary为nil时使用boil.GetDB()
+func NewCluster(primary boil.Executor, options ...ClusterOption) *Cluster {
+	c := &Cluster{
+		primary:  primary,
+		replicas: make([]*replica, 0),
+	}
+	for _, apply := range options {
+		apply(c)
+	}
+	return c
+}
+
+// WithReplica 添加从库, weight只在按权重选择时有效, 小于等于0视为1
+func WithReplica(executor boil.Executor, weight ...int) ClusterOption {
+	return func(c *Cluster) {
+		w := 1
+		if len(weight) > 0 && weight[0] > 0 {
+			w = weight[0]
+		}
+
+		r := &replica{executor: executor, weight: w}
+		r.healthy.Store(true)
+		c.replicas = append(c.replicas, r)
+	}
+}
+
+// WithReadPolicy 设置从库选择策略
+func WithReadPolicy(policy ReadPolicy) ClusterOption {
+	return func(c *Cluster) {
+		c.policy = policy
+	}
+}
+
+// SetCluster 设置默认数据库的集群
+func SetCluster(c *Cluster) {
+	RegisterDatabase(DefaultDatabase, c)
+}
+
+// RegisterDatabase 注册命名数据库, NewGdb/NewTdb/NewTransactor可以通过名字访问该数据库
+func RegisterDatabase(name string, c *Cluster) {
+	clusters.Store(name, c)
+}
+
+// getCluster 获取命名数据库的集群, 默认数据库未注册时使用boil.GetDB()
+func getCluster(name string) (*Cluster, error) {
+	if v, exist := clusters.Load(name); exist {
+		return v.(*Cluster), nil
+	}
+
+	if name == DefaultDatabase {
+		return fallbackCluster, nil
+	}
+	return nil, fmt.Errorf("database not registered: %s", name)
+}
+
+// getDatabase 获取可选参数中的数据库名, 未指定时为默认数据库
+func getDatabase(args ...string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return DefaultDatabase
+}
+
+// resolveCluster 获取命名数据库的集群, 数据库未注册时记录错误日志并返回一个错误集群,
+// 错误集群上的任何数据库操作都会返回未注册的错误, 而不是在获取Executor时panic
+func resolveCluster(name string) *Cluster {
+	c, err := getCluster(name)
+	if err == nil {
+		return c
+	}
+
+	loggerUtils.Error("resolve database", "database", name, "err", err)
+	v, _ := errClusters.LoadOrStore(name, &Cluster{primary: sql.OpenDB(errConnector{err: err})})
+	return v.(*Cluster)
+}
+
+// ReadYourWrites 在context中设置读己之写标记, Db.Reader会强制使用主库
+func ReadYourWrites(c context.Context) context.Context {
+	return context.WithValue(c, readYourWritesKey{}, true)
+}
+
+// Primary 获取主库
+func (c *Cluster) Primary() boil.Executor {
+	if c.primary != nil {
+		return c.primary
+	}
+	return boil.GetDB()
+}
+
+// beginTx 在主库上开启事务
+func (c *Cluster) beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	if c.primary == nil {
+		return boil.BeginTx(ctx, opts)
+	}
+
+	beginner, ok := c.primary.(boil.ContextBeginner)
+	if !ok {
+		return nil, errors.New("database does not support context-aware transactions")
+	}
+	return beginner.BeginTx(ctx, opts)
+}
+
+// Replica 按策略选择一个健康的从库, 没有可用的从库时返回主库
+func (c *Cluster) Replica() boil.Executor {
+	var r *replica
+	switch c.policy {
+	case ReadPolicyWeighted:
+		r = c.pickWeighted()
+	default:
+		r = c.pickRoundRobin()
+	}
+
+	if r == nil {
+		return c.Primary()
+	}
+	return r.executor
+}
+
+// SetHealthy 标记从库是否健康, 不健康的从库不会被选择
+func (c *Cluster) SetHealthy(executor boil.Executor, healthy bool) {
+	for _, r := range c.replicas {
+		if r.executor == executor {
+			r.healthy.Store(healthy)
+		}
+	}
+}
+
+// CheckHealth 对支持PingContext的从库进行健康检查并更新其状态, 可以定时调用
+func (c *Cluster) CheckHealth(ctx context.Context) {
+	for _, r := range c.replicas {
+		if pinger, ok := r.executor.(interface{ PingContext(context.Context) error }); ok {
+			r.healthy.Store(pinger.PingContext(ctx) == nil)
+		}
+	}
+}
+
+func (c *Cluster) pickRoundRobin() *replica {
+	total := len(c.replicas)
+	if total == 0 {
+		return nil
+	}
+
+	start := c.next.Add(1)
+	for i := 0; i < total; i++ {
+		if r := c.replicas[(start+uint64(i))%uint64(total)]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) pickWeighted() *replica {
+	totalWeight := 0
+	for _, r := range c.replicas {
+		if r.healthy.Load() {
+			totalWeight += r.weight
+		}
+	}
+	if totalWeight == 0 {
+		return nil
+	}
+
+	n := rand.IntN(totalWeight)
+	for _, r := range c.replicas {
+		if !r.healthy.Load() {
+			continue
+		}
+		if n < r.weight {
+			return r
+		}
+		n -= r.weight
+	}
+	return nil
+}
+
+func isReadYourWrites(c context.Context) bool {
+	if c == nil {
+		return false
+	}
+	v, _ := c.Value(readYourWritesKey{}).(bool)
+	return v
+}
+
+// errConnector 建立连接时总是返回错误, 用于未注册的数据库
+type errConnector struct {
+	err error
+}
+
+func (c errConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, c.err
+}
+
+func (c errConnector) Driver() driver.Driver {
+	return c
+}
+
+func (c errConnector) Open(string) (driver.Conn, error) {
+	return nil, c.err
+}
@@ -0,0 +1,49 @@
+package sqlboiler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+func TestRegisterDatabase(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	// 没有从库时读写都使用注册的主库
+	gdb := NewGdb(ctx, database)
+	if gdb.Executor() != boil.Executor(db) || gdb.Reader(context.Background()) != boil.Executor(db) {
+		t.Fatal("registered database not used")
+	}
+
+	// 未注册的数据库在执行时返回错误, 而不是panic
+	const missing = "missing"
+	t.Cleanup(func() {
+		errClusters.Delete(missing)
+	})
+	gdb = NewGdb(ctx, missing)
+	if _, err := gdb.Executor().Exec("SELECT 1"); err == nil || !strings.Contains(err.Error(), "database not registered: missing") {
+		t.Fatalf("expect not registered error, got: %v", err)
+	}
+	var n int
+	if err := gdb.Reader(context.Background()).QueryRow("SELECT 1").Scan(&n); err == nil || !strings.Contains(err.Error(), "database not registered: missing") {
+		t.Fatalf("expect not registered error, got: %v", err)
+	}
+	if resolveCluster(missing) != resolveCluster(missing) {
+		t.Fatal("error cluster not reused")
+	}
+	if _, err := NewTransactor(ctx, nil, WithDatabase(missing)); err == nil || !strings.Contains(err.Error(), "database not registered: missing") {
+		t.Fatalf("expect not registered error, got: %v", err)
+	}
+
+	// 注册后新建的Db使用注册的数据库
+	RegisterDatabase(missing, NewCluster(db))
+	t.Cleanup(func() {
+		clusters.Delete(missing)
+	})
+	if NewGdb(ctx, missing).Executor() != boil.Executor(db) {
+		t.Fatal("registered database not used")
+	}
+}
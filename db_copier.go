@@ -2,83 +2,179 @@ package sqlboiler
 
 import (
 	"fmt"
+	"maps"
 	"math"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/aarondl/null/v8"
 	"github.com/aarondl/sqlboiler/v4/types"
 	"github.com/elliotchance/pie/v2"
-	jsonUtils "github.com/hdget/utils/json"
-	"github.com/hdget/utils/text"
 	"github.com/iancoleman/strcase"
 	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type DbCopier interface {
-	Blacklist(fieldNames ...string) DbCopier                               // 设置黑名单字段
-	Whitelist(fieldNames ...string) DbCopier                               // 设置白名单字段
-	AutoIncr(fieldNames ...string) DbCopier                                // 设置自增字段
-	JSONArray(fieldNames ...string) DbCopier                               // 设置为Json数组的字段
-	JSONObject(fieldNames ...string) DbCopier                              // 设置Json字段的处理函数为Json数组
-	Copy(destObject any, source any) error                                 // 将source值填入到modelObject中
-	CopyForCreate(destObject any, source any, allowFields ...string) error // 创建动作需要的复制
-	CopyForEdit(destObject any, source any, allowFields ...string) error   // 创建动作需要的复制
+	Blacklist(fieldNames ...string) DbCopier                                                // 设置黑名单字段
+	Whitelist(fieldNames ...string) DbCopier                                                // 设置白名单字段
+	AutoIncr(fieldNames ...string) DbCopier                                                 // 设置自增字段
+	JSONArray(fieldNames ...string) DbCopier                                                // 设置为Json数组的字段
+	JSONObject(fieldNames ...string) DbCopier                                               // 设置Json字段的处理函数为Json数组
+	Map(srcField, destField string) DbCopier                                                // 设置源字段到目标字段的映射
+	Copy(destObject any, source any) error                                                  // 将source值填入到modelObject中, 支持slice之间的拷贝
+	CopyForCreate(destObject any, source any, allowFields ...string) error                  // 创建动作需要的复制
+	CopyForEdit(destObject any, source any, allowFields ...string) error                    // 创建动作需要的复制
+	CopyForEditColumns(destObject any, source any, allowFields ...string) ([]string, error) // 编辑动作需要的复制, 返回写入的字段名
+	FieldMask(mask *fieldmaskpb.FieldMask) DbCopier                                         // 只拷贝FieldMask中列出的源字段
+	Presence() DbCopier                                                                     // 忽略protobuf消息中未设置的optional字段
+	OnlyChanged() DbCopier                                                                  // CopyForEditColumns只返回值发生变化的字段
+	JSONSchema(fieldName string, schema JsonSchema) DbCopier                                // 设置Json字段的Schema校验
+	TimeLayouts(layouts ...string) DbCopier                                                 // 设置字符串解析为时间时接受的格式
+	TimeLocation(loc *time.Location) DbCopier                                               // 设置解析和格式化时间使用的时区
+	Validate() DbCopier                                                                     // 拷贝时按validate tag校验字段值
+	Validator(fieldName string, validators ...FieldValidator) DbCopier                      // 设置字段的校验函数
+	Default(fieldName string, value any) DbCopier                                           // 设置字段为零值时的默认值
+	Provide(fieldName string, provider ValueProvider) DbCopier                              // 设置创建和编辑时的计算字段
+	ProvideOnCreate(fieldName string, provider ValueProvider) DbCopier                      // 设置创建时的计算字段
+	Strict() DbCopier                                                                       // 严格模式, 未知字段和有损转换都视为错误, 返回所有出错的字段
+	CopyToDTO(destObject any, source any) error                                             // 将model拷贝到响应DTO, 进行反向转换
+}
+
+// CopierProfile DbCopier的应用级别配置
+type CopierProfile struct {
+	CreateSkipFields []string // 创建时默认忽略的字段
+	EditSkipFields   []string // 编辑时默认忽略的字段
+	AutoIncrFields   []string // 默认的自增字段
+	TimeLayouts      []string // 字符串解析为时间时接受的格式, 为空时使用默认格式
+}
+
+// copierProfile 编译后的配置, 创建后不再修改, 可以在多个copier之间共享
+type copierProfile struct {
+	id               uint64 // 配置的唯一标识, 用于区分拷贝计划
+	createSkipFields map[string]struct{}
+	editSkipFields   map[string]struct{}
+	autoIncrFields   map[string]struct{}
+	timeLayouts      []string
 }
 
 type dbCopierImpl struct {
+	profile          *copierProfile
+	matchKey         string // 影响字段匹配的选项的标识, 修改选项时重新生成, 用于缓存拷贝计划
 	blacklistFields  map[string]struct{}
 	whitelistFields  map[string]struct{}
 	autoIncrFields   map[string]struct{}
 	jsonArrayFields  map[string]struct{}
 	jsonObjectFields map[string]struct{}
+	fieldMappings    map[string]string           // 源字段到目标字段的映射
+	jsonSchemas      map[string]JsonSchema       // Json字段的Schema
+	onlyChanged      bool                        // 是否只记录值发生变化的字段
+	maskFields       map[string]struct{}         // FieldMask中的源字段, nil表示未设置FieldMask
+	presence         bool                        // 是否忽略protobuf消息中未设置的字段
+	strict           bool                        // 是否为严格模式
+	reverse          bool                        // 是否为model到DTO的反向拷贝
+	timeLayouts      []string                    // 字符串解析为时间时接受的格式
+	location         *time.Location              // 解析和格式化时间使用的时区, nil表示使用boil.GetLocation()
+	validate         bool                        // 是否按validate tag校验字段值
+	validators       map[string][]FieldValidator // 字段的校验函数
+	providers        map[string]fieldProvider    // 计算字段
+	defaults         map[string]any              // 字段的默认值
+}
+
+// copyState 一次拷贝的状态, 每次拷贝单独创建, 拷贝过程中不会修改copier
+type copyState struct {
+	action       copyAction          // 拷贝动作, 决定是否执行默认值和计算字段
+	blacklist    map[string]struct{} // 本次拷贝生效的黑名单
+	allowKey     string              // 本次拷贝允许的字段, 用于区分拷贝计划
+	trackColumns bool                // 是否记录写入的目标字段
+	onlyChanged  bool                // 是否只记录值发生变化的字段
+	columns      []string            // 写入的目标字段
+	absentFields map[string]struct{} // protobuf消息中未设置的字段
+	strict       bool                // 严格模式下收集所有字段错误
+	prefix       string              // 当前字段路径前缀, 用于slice元素, e.g: [1].
+	errs         []*FieldError       // 严格模式下收集到的字段错误
+	invalid      []*FieldError       // 校验失败的字段
 }
 
 // 预定义常用类型反射对象避免重复创建
 var (
 	timeType           = reflect.TypeOf(time.Time{})
+	mapType            = reflect.TypeOf(map[string]any{})
+	jsonType           = reflect.TypeOf(types.JSON{})
+	nullJsonType       = reflect.TypeOf(null.JSON{})
+	nullPkgPath        = reflect.TypeOf(null.String{}).PkgPath()
 	errOverflow        = errors.New("integer overflow")
+	errFloatOverflow   = errors.New("float overflow")
+	errTruncated       = errors.New("float truncated")
+	errUnknownField    = errors.New("unknown field")
 	errUnsupportedType = errors.New("unsupported field type for increment")
-
-	// editSkipFields 编辑时默认忽略的字段
-	createSkipFields = map[string]struct{}{
-		"created_at": {},
-		"updated_at": {},
-		"version":    {},
-		"tid":        {},
-		"deleted_at": {},
-		"r":          {},
-		"l":          {},
-	}
-
-	// editSkipFields 编辑时默认忽略的字段
-	editSkipFields = map[string]struct{}{
-		"created_at": {},
-		"updated_at": {},
-		"version":    {},
-		"tid":        {},
-		"id":         {},
-		"sn":         {},
-		"deleted_at": {},
-		"r":          {},
-		"l":          {},
-	}
-
-	// 自增字段
-	defaultAutoIncrFields = map[string]struct{}{
-		"version": {},
+	errNilElement      = errors.New("source element is nil")
+	errNilValue        = errors.New("source value is nil")
+
+	// 字段匹配时依次查找的tag
+	fieldTagNames = []string{"copier", "boil", "json"}
+
+	// defaultCopierProfile 默认配置, 不可修改, 应用可以通过SetCopierProfile替换
+	defaultCopierProfile = CopierProfile{
+		CreateSkipFields: []string{"created_at", "updated_at", "version", "tid", "deleted_at", "r", "l"},
+		EditSkipFields:   []string{"created_at", "updated_at", "version", "tid", "id", "sn", "deleted_at", "r", "l"},
+		AutoIncrFields:   []string{"version"},
+		TimeLayouts:      []string{time.DateTime, time.RFC3339Nano, time.DateOnly, "2006-01-02T15:04:05"},
 	}
+
+	// currentCopierProfile 当前生效的配置, 只读, 新建的copier会使用该配置
+	currentCopierProfile atomic.Pointer[copierProfile]
+	profileSeq           atomic.Uint64
 )
 
+func init() {
+	SetCopierProfile(defaultCopierProfile)
+}
+
+// DefaultCopierProfile 获取默认配置的副本, 可以在其基础上修改后调用SetCopierProfile
+func DefaultCopierProfile() CopierProfile {
+	return CopierProfile{
+		CreateSkipFields: slices.Clone(defaultCopierProfile.CreateSkipFields),
+		EditSkipFields:   slices.Clone(defaultCopierProfile.EditSkipFields),
+		AutoIncrFields:   slices.Clone(defaultCopierProfile.AutoIncrFields),
+		TimeLayouts:      slices.Clone(defaultCopierProfile.TimeLayouts),
+	}
+}
+
+// SetCopierProfile 设置应用级别的copier配置, 只影响之后新建的copier, 一般在应用启动时调用
+func SetCopierProfile(profile CopierProfile) {
+	currentCopierProfile.Store(&copierProfile{
+		id:               profileSeq.Add(1),
+		createSkipFields: toFieldSet(profile.CreateSkipFields),
+		editSkipFields:   toFieldSet(profile.EditSkipFields),
+		autoIncrFields:   toFieldSet(profile.AutoIncrFields),
+		timeLayouts:      slices.Clone(profile.TimeLayouts),
+	})
+}
+
 func newDbCopier() DbCopier {
-	return &dbCopierImpl{
-		autoIncrFields:   defaultAutoIncrFields,
+	profile := currentCopierProfile.Load()
+	impl := &dbCopierImpl{
+		profile:          profile,
+		autoIncrFields:   maps.Clone(profile.autoIncrFields),
+		timeLayouts:      profile.timeLayouts,
 		blacklistFields:  make(map[string]struct{}),
 		whitelistFields:  make(map[string]struct{}),
 		jsonArrayFields:  make(map[string]struct{}),
 		jsonObjectFields: make(map[string]struct{}),
+		fieldMappings:    make(map[string]string),
+		jsonSchemas:      make(map[string]JsonSchema),
+		validators:       make(map[string][]FieldValidator),
+		providers:        make(map[string]fieldProvider),
+		defaults:         make(map[string]any),
 	}
+	impl.updateMatchKey()
+	return impl
 }
 
 // Blacklist 目标对象中除去blacklist的字段都会尝试拷贝
@@ -91,6 +187,7 @@ func (impl *dbCopierImpl) Blacklist(fields ...string) DbCopier {
 		impl.blacklistFields[field] = struct{}{}
 	}
 
+	impl.updateMatchKey()
 	return impl
 }
 
@@ -104,6 +201,7 @@ func (impl *dbCopierImpl) Whitelist(fields ...string) DbCopier {
 		impl.whitelistFields[field] = struct{}{}
 	}
 
+	impl.updateMatchKey()
 	return impl
 }
 
@@ -144,164 +242,459 @@ func (impl *dbCopierImpl) JSONObject(fields ...string) DbCopier {
 	return impl
 }
 
+// JSONSchema 设置Json字段的Schema, 写入前校验Json内容, 只对JSONArray/JSONObject字段有效
+func (impl *dbCopierImpl) JSONSchema(field string, schema JsonSchema) DbCopier {
+	impl.jsonSchemas[format(field)] = schema
+	return impl
+}
+
+// TimeLayouts 设置字符串解析为时间时依次尝试的格式, 反向拷贝时使用第一个格式格式化时间
+func (impl *dbCopierImpl) TimeLayouts(layouts ...string) DbCopier {
+	impl.timeLayouts = layouts
+	return impl
+}
+
+// TimeLocation 设置解析没有时区的时间字符串和时间戳时使用的时区, 默认使用sqlboiler的boil.GetLocation()
+func (impl *dbCopierImpl) TimeLocation(loc *time.Location) DbCopier {
+	impl.location = loc
+	return impl
+}
+
+// Map 将源对象中的srcField字段拷贝到目标对象的destField字段, 用于无法修改struct tag的类型
+// srcField可以是点号路径, 用于将嵌套结构体的字段展开到目标对象, e.g: Map("Address.City", "city"), 只对struct源对象有效
+func (impl *dbCopierImpl) Map(srcField, destField string) DbCopier {
+	srcPath := pie.Map(strings.Split(srcField, "."), func(v string) string {
+		return format(v)
+	})
+	impl.fieldMappings[strings.Join(srcPath, ".")] = format(destField)
+	impl.updateMatchKey()
+	return impl
+}
+
 func (impl *dbCopierImpl) CopyForCreate(dest any, src any, allowFields ...string) error {
-	impl.blacklistFields = createSkipFields
-	for _, field := range allowFields {
-		delete(impl.blacklistFields, field)
-	}
-	return impl.Copy(dest, src)
+	return impl.execute(dest, src, &copyState{
+		action:    copyActionCreate,
+		blacklist: impl.mergeBlacklist(impl.profile.createSkipFields, allowFields),
+		allowKey:  joinFields(allowFields),
+		strict:    impl.strict,
+	})
 }
 
 func (impl *dbCopierImpl) CopyForEdit(dest any, src any, allowFields ...string) error {
-	impl.blacklistFields = editSkipFields
-	for _, field := range allowFields {
-		delete(impl.blacklistFields, field)
+	_, err := impl.CopyForEditColumns(dest, src, allowFields...)
+	return err
+}
+
+// CopyForEditColumns 编辑动作需要的复制, 返回写入的目标字段名, 可直接用于boil.Whitelist(cols...)
+// 设置了OnlyChanged时只返回值发生变化的字段, 返回空表示无需更新
+func (impl *dbCopierImpl) CopyForEditColumns(dest any, src any, allowFields ...string) ([]string, error) {
+	state := &copyState{
+		action:       copyActionEdit,
+		blacklist:    impl.mergeBlacklist(impl.profile.editSkipFields, allowFields),
+		allowKey:     joinFields(allowFields),
+		trackColumns: true,
+		onlyChanged:  impl.onlyChanged,
+		columns:      make([]string, 0),
+		strict:       impl.strict,
+	}
+	if err := impl.execute(dest, src, state); err != nil {
+		return nil, err
+	}
+	return state.columns, nil
+}
+
+// FieldMask 只拷贝FieldMask中列出的源字段, 嵌套路径只取第一级, e.g: address.city => address
+// nil或者没有路径的FieldMask表示客户端没有指定, 不限制拷贝的字段, 可以直接传入req.GetUpdateMask()
+func (impl *dbCopierImpl) FieldMask(mask *fieldmaskpb.FieldMask) DbCopier {
+	if len(mask.GetPaths()) == 0 {
+		return impl
 	}
-	return impl.Copy(dest, src)
+
+	if impl.maskFields == nil {
+		impl.maskFields = make(map[string]struct{})
+	}
+
+	for _, path := range mask.GetPaths() {
+		field, _, _ := strings.Cut(path, ".")
+		impl.maskFields[format(field)] = struct{}{}
+	}
+	return impl
+}
+
+// Presence 源对象为protobuf消息时, 忽略支持presence(如proto3 optional)但未设置的字段
+// 这样可以区分"客户端没有发送该字段"和"客户端发送了零值"
+func (impl *dbCopierImpl) Presence() DbCopier {
+	impl.presence = true
+	return impl
+}
+
+// OnlyChanged CopyForEditColumns只返回值发生变化的字段
+func (impl *dbCopierImpl) OnlyChanged() DbCopier {
+	impl.onlyChanged = true
+	return impl
+}
+
+// Strict 严格模式, 源map中没有对应目标字段的key, 浮点数截断, 整数溢出和无法解析的值都视为错误,
+// 拷贝不会在第一个错误处停止, 而是返回包含所有出错字段的*CopyError
+func (impl *dbCopierImpl) Strict() DbCopier {
+	impl.strict = true
+	return impl
 }
 
 func (impl *dbCopierImpl) Copy(dest any, src any) error {
+	return impl.execute(dest, src, &copyState{blacklist: impl.blacklistFields, strict: impl.strict})
+}
+
+// execute 执行拷贝, 严格模式下返回收集到的所有字段错误, 然后是所有校验失败的字段
+func (impl *dbCopierImpl) execute(dest any, src any, state *copyState) error {
+	if err := impl.copy(dest, src, state); err != nil {
+		return err
+	}
+	if err := state.err(); err != nil {
+		return err
+	}
+	return state.invalidErr()
+}
+
+// CopyToDTO 将model拷贝到响应DTO, 使用同样的字段匹配规则, 但进行反向转换:
+// types.JSON, null.JSON和设置为Json的字段反序列化为嵌套消息, map或者slice, 自增字段不再自增, 无效的null.*转换为零值
+func (impl *dbCopierImpl) CopyToDTO(dest any, src any) error {
+	reversed := *impl
+	reversed.reverse = true
+	return reversed.execute(dest, src, &copyState{blacklist: impl.blacklistFields, strict: impl.strict})
+}
+
+// copyNested 递归拷贝嵌套结构体, 不使用字段选项, 但保持严格模式, 拷贝方向, 时间设置和tag校验
+func (impl *dbCopierImpl) copyNested(dest any, src any) error {
+	nested := &dbCopierImpl{
+		strict:      impl.strict,
+		reverse:     impl.reverse,
+		timeLayouts: impl.timeLayouts,
+		location:    impl.location,
+		validate:    impl.validate,
+	}
+	return nested.execute(dest, src, &copyState{strict: impl.strict})
+}
+
+// mergeBlacklist 合并默认忽略的字段和用户设置的黑名单, 并去掉允许的字段, 不会修改原有的集合
+func (impl *dbCopierImpl) mergeBlacklist(skipFields map[string]struct{}, allowFields []string) map[string]struct{} {
+	blacklist := make(map[string]struct{}, len(skipFields)+len(impl.blacklistFields))
+	maps.Copy(blacklist, skipFields)
+	maps.Copy(blacklist, impl.blacklistFields)
+	for _, field := range allowFields {
+		delete(blacklist, format(field))
+	}
+	return blacklist
+}
+
+func (impl *dbCopierImpl) copy(dest any, src any, state *copyState) error {
 	to, isPtr := indirect(reflect.ValueOf(dest))
+	if isPtr && to.Kind() == reflect.Slice {
+		return impl.copySlice(to, src, state)
+	}
+
 	toType, _ := indirectType(to.Type())
 	if !isPtr || toType.Kind() != reflect.Struct {
-		return fmt.Errorf("dest must be a point of struct")
+		return fmt.Errorf("dest must be a point of struct or slice")
 	}
 
 	from, _ := indirect(reflect.ValueOf(src))
 	fromType, _ := indirectType(from.Type())
 
+	if impl.presence {
+		state.absentFields = getAbsentProtoFields(src)
+	}
+
+	var err error
 	switch fromType.Kind() {
 	case reflect.Struct:
-		return impl.copyFromStruct(to, toType, from, fromType)
+		err = impl.copyFromStruct(to, toType, from, fromType, state)
 	case reflect.Map:
-		return impl.copyFromMap(to, src)
+		err = impl.copyFromMap(to, src, state)
 	default:
 		return fmt.Errorf("unsupported src type %v", fromType.Name())
 	}
+	if err != nil {
+		return err
+	}
+
+	if err = impl.applyProviders(to, state); err != nil {
+		return err
+	}
+
+	return impl.validateMissing(to, state)
+}
+
+// copySlice 将源slice逐个元素拷贝到目标slice, 目标元素可以是struct或struct指针, e.g: []*pb.Item => models.ItemSlice
+// 每个元素使用相同的选项, 出错时返回元素下标, 源元素为nil时返回错误, 避免目标slice中出现nil元素
+func (impl *dbCopierImpl) copySlice(to reflect.Value, src any, state *copyState) error {
+	from, _ := indirect(reflect.ValueOf(src))
+	if from.Kind() != reflect.Slice && from.Kind() != reflect.Array {
+		return fmt.Errorf("src must be a slice when dest is a slice, got %v", from.Kind())
+	}
+
+	elemType := to.Type().Elem()
+	structType, isPtrElem := elemType, elemType.Kind() == reflect.Ptr
+	if isPtrElem {
+		structType = elemType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("dest element must be a struct or point of struct, got %v", elemType)
+	}
+
+	result := reflect.MakeSlice(to.Type(), from.Len(), from.Len())
+	for i := 0; i < from.Len(); i++ {
+		srcElem := from.Index(i)
+		if (srcElem.Kind() == reflect.Ptr || srcElem.Kind() == reflect.Interface) && srcElem.IsNil() {
+			return errors.Wrapf(errNilElement, "copy element at index %d", i)
+		}
+
+		destElem := result.Index(i).Addr()
+		if isPtrElem {
+			result.Index(i).Set(reflect.New(structType))
+			destElem = result.Index(i)
+		}
+
+		prefix := state.prefix
+		state.prefix = fmt.Sprintf("%s[%d].", prefix, i)
+		err := impl.copy(destElem.Interface(), srcElem.Interface(), state)
+		state.prefix = prefix
+		if err != nil {
+			return errors.Wrapf(err, "copy element at index %d", i)
+		}
+	}
+
+	to.Set(result)
+	return nil
 }
 
 // copyFromMap 不区分大小写
-func (impl *dbCopierImpl) copyFromMap(to reflect.Value, from any) error {
+func (impl *dbCopierImpl) copyFromMap(to reflect.Value, from any, state *copyState) error {
 	props, ok := from.(map[string]any)
 	if !ok {
 		return errors.New("source is not map[string]any")
 	}
 
+	destInfo := getTypeInfo(to.Type())
 	for key, value := range props {
-		formattedKey := format(key)
+		srcKey := cachedFormat(key)
+		if impl.absent(srcKey, state) {
+			continue
+		}
 
-		if len(impl.whitelistFields) > 0 { // 如果有白名单，不在白名单中的字段都忽略, 优先级高
-			if _, exist := impl.whitelistFields[formattedKey]; !exist {
-				continue
-			}
-		} else { // 黑名单优先级低
-			if _, exist := impl.blacklistFields[formattedKey]; exist {
-				continue
+		formattedKey := impl.mapKey(srcKey)
+		if impl.skip(formattedKey, state) {
+			continue
+		}
+
+		index, exist := destInfo.key2index[formattedKey]
+		if !exist {
+			if state.strict {
+				state.addError(key, errUnknownField)
 			}
+			continue
 		}
 
-		destField := to.FieldByNameFunc(func(field string) bool {
-			return format(field) == formattedKey
-		})
+		destField := fieldByIndexAlloc(to, index)
 		if !destField.IsValid() || !destField.CanSet() {
 			continue // 忽略无效或不可导出字段
 		}
 
-		// 类型转换并设置字段值
-		if _, exist := impl.autoIncrFields[formattedKey]; exist {
-			if err := impl.incrField(destField, value); err != nil {
-				return errors.Wrap(err, "increase field value")
-			}
-		} else if _, exist := impl.jsonObjectFields[formattedKey]; exist {
-			impl.handleJsonField(destField, value, jsonUtils.JsonObject)
-		} else if _, exist := impl.jsonArrayFields[formattedKey]; exist {
-			impl.handleJsonField(destField, value, jsonUtils.JsonArray)
-		} else {
-			if err := impl.setField(destField, reflect.ValueOf(value), value); err != nil {
-				return errors.Wrapf(err, "set field '%s'", destField.Type().Name())
-			}
+		if err := impl.copyField(destField, formattedKey, destInfo.key2name[formattedKey], destInfo.rules[formattedKey], reflect.ValueOf(value), state); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (impl *dbCopierImpl) copyFromStruct(to reflect.Value, toType reflect.Type, from reflect.Value, fromType reflect.Type) error {
-	// 收集需要拷贝的字段
-	srcFieldName2srcField := make(map[string]reflect.Value)
-	for i := 0; i < from.NumField(); i++ {
-		srcField := from.Field(i)
-		srcFieldName := fromType.Field(i).Name
-
-		srcFormattedFieldName := format(srcFieldName)
+func (impl *dbCopierImpl) copyFromStruct(to reflect.Value, toType reflect.Type, from reflect.Value, fromType reflect.Type, state *copyState) error {
+	for _, f := range impl.getCopyPlan(fromType, toType, state).fields {
+		if impl.absent(f.srcKey, state) {
+			continue
+		}
 
-		if len(impl.whitelistFields) > 0 { // 如果有白名单，不在白名单中的字段都忽略, 优先级高
-			if _, exist := impl.whitelistFields[srcFormattedFieldName]; !exist {
-				continue
-			}
-		} else { // 黑名单优先级低, 在黑名单中的都忽略
-			if _, exist := impl.blacklistFields[srcFormattedFieldName]; exist {
-				continue
-			}
+		srcField := fieldByIndex(from, f.srcIndex)
+		if !srcField.IsValid() {
+			continue // 路径中的结构体指针为nil
 		}
 
-		if !text.IsCapitalized(srcFieldName) || // 过滤未导出的字段
-			!isSupportedType(srcField.Type()) { // 过滤不支持的类型
+		destField := fieldByIndexAlloc(to, f.destIndex)
+		if !destField.IsValid() || !destField.CanSet() {
 			continue
 		}
 
-		srcFieldName2srcField[srcFormattedFieldName] = srcField
+		if err := impl.copyField(destField, f.destKey, f.destName, f.rules, srcField, state); err != nil {
+			return err
+		}
 	}
 
-	for i := 0; i < to.NumField(); i++ {
-		destField := to.Field(i)
-		destFieldName := toType.Field(i).Name
+	return nil
+}
 
-		if !destField.IsValid() || !destField.CanSet() {
-			continue
+// copyField 类型转换并设置字段值, 如果需要则校验字段值并记录写入的字段
+// key为用于查找选项的字段名, name为原样的字段名, 用于错误路径, rules为字段的validate tag
+func (impl *dbCopierImpl) copyField(destField reflect.Value, key, name, rules string, srcField reflect.Value, state *copyState) error {
+	var oldValue any
+	if state.onlyChanged {
+		oldValue = destField.Interface()
+	}
+
+	var srcValue any
+	if srcField.IsValid() {
+		srcValue = srcField.Interface()
+	}
+
+	if _, exist := impl.autoIncrFields[key]; exist && !impl.reverse {
+		if err := impl.incrField(destField, srcValue); err != nil {
+			return state.fieldError(name, err, "increase field")
+		}
+	} else if impl.reverse && impl.isJsonField(key) {
+		if err := impl.unmarshalJsonField(destField, srcValue); err != nil {
+			return state.fieldError(name, err, "copy from json field")
+		}
+	} else if _, exist := impl.jsonObjectFields[key]; exist {
+		if err := impl.handleJsonField(destField, key, srcValue, jsonFieldObject); err != nil {
+			return state.fieldError(name, err, "copy to json field")
+		}
+	} else if _, exist := impl.jsonArrayFields[key]; exist {
+		if err := impl.handleJsonField(destField, key, srcValue, jsonFieldArray); err != nil {
+			return state.fieldError(name, err, "copy to json field")
+		}
+	} else {
+		srcField, _ = indirect(srcField)
+		srcValue = nil
+		if srcField.IsValid() {
+			srcValue = srcField.Interface()
 		}
 
-		destFormattedFieldName := format(destFieldName)
-		if srcField, exists := srcFieldName2srcField[destFormattedFieldName]; exists {
-			// 类型转换并设置字段值
-			if _, exist := impl.autoIncrFields[destFormattedFieldName]; exist {
-				if err := impl.incrField(destField, srcField.Interface()); err != nil {
-					return errors.Wrap(err, "increase struct field value")
-				}
-			} else if _, exist := impl.jsonObjectFields[destFormattedFieldName]; exist {
-				impl.handleJsonField(destField, srcField.Interface(), jsonUtils.JsonObject)
-			} else if _, exist := impl.jsonArrayFields[destFormattedFieldName]; exist {
-				impl.handleJsonField(destField, srcField.Interface(), jsonUtils.JsonArray)
-			} else {
-				srcField, _ = indirect(srcField)
-				var srcValue any
-				if srcField.IsValid() {
-					srcValue = srcField.Interface()
-				}
-				if err := impl.setField(destField, srcField, srcValue); err != nil {
-					return errors.Wrapf(err, "copy to struct field '%s'", destFieldName)
-				}
+		// 源值为nil(nil指针或者map中的null)时只将null.*类型的目标字段设置为无效值, 其他目标字段保持不变, 也不记录为写入的字段
+		if srcValue == nil {
+			if !isNullType(destField.Type()) {
+				return nil
 			}
-
+			destField.Set(reflect.Zero(destField.Type()))
+		} else if err := impl.setField(destField, srcField, srcValue); err != nil {
+			// 嵌套拷贝的校验错误合并到当前拷贝中
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				return state.fieldError(name, err, "copy to field")
+			}
+			state.mergeInvalid(name, validationErr)
 		}
 	}
 
+	if err := impl.validateField(destField, key, name, rules, state); err != nil {
+		return err
+	}
+
+	state.track(name, oldValue, destField)
 	return nil
 }
 
+// track 记录写入的目标字段, 使用原样的字段名, 可以直接用于boil.Whitelist, 设置了OnlyChanged时只记录值发生变化的字段
+func (s *copyState) track(name string, oldValue any, destField reflect.Value) {
+	if !s.trackColumns {
+		return
+	}
+	if s.onlyChanged && isEqualValue(oldValue, destField.Interface()) {
+		return
+	}
+	if !slices.Contains(s.columns, name) {
+		s.columns = append(s.columns, name)
+	}
+}
+
+// mapKey 获取源字段映射后的目标字段名
+func (impl *dbCopierImpl) mapKey(key string) string {
+	if mapped, exist := impl.fieldMappings[key]; exist {
+		return mapped
+	}
+	return key
+}
+
+// absent 判断源字段是否未出现, 即不在FieldMask中或者protobuf消息中未设置
+func (impl *dbCopierImpl) absent(srcKey string, state *copyState) bool {
+	if impl.maskFields != nil {
+		if _, exist := impl.maskFields[srcKey]; !exist {
+			return true
+		}
+	}
+	_, exist := state.absentFields[srcKey]
+	return exist
+}
+
+// skip 判断目标字段是否需要忽略, 如果有白名单，不在白名单中的字段都忽略, 优先级高, 否则忽略黑名单中的字段
+func (impl *dbCopierImpl) skip(key string, state *copyState) bool {
+	if len(impl.whitelistFields) > 0 {
+		_, exist := impl.whitelistFields[key]
+		return !exist
+	}
+	_, exist := state.blacklist[key]
+	return exist
+}
+
 func (impl *dbCopierImpl) setField(destField reflect.Value, srcField reflect.Value, srcFieldValue any) error {
+	if srcFieldValue == nil {
+		return errNilValue
+	}
+
+	// 优先使用注册的类型转换函数
+	if converted, ok, err := convertValue(srcField, destField.Type()); ok {
+		if err != nil {
+			return err
+		}
+		destField.Set(converted)
+		return nil
+	}
+
 	// 快速路径：类型完全匹配
 	if srcFieldValue != nil && srcField.Type().AssignableTo(destField.Type()) {
 		destField.Set(srcField)
 		return nil
 	}
 
-	// 次快路径：类型可转换
-	if srcFieldValue != nil && srcField.Type().ConvertibleTo(destField.Type()) {
+	// 次快路径：类型可转换, 严格模式下不同类型的数字需要检查是否有损, 整数转字符串不能使用Go的rune转换
+	if srcFieldValue != nil && srcField.Type().ConvertibleTo(destField.Type()) &&
+		!(impl.strict && isNumberKind(srcField.Kind()) && isNumberKind(destField.Kind()) && srcField.Kind() != destField.Kind()) &&
+		!(isNumberKind(srcField.Kind()) && destField.Kind() == reflect.String) {
 		destField.Set(srcField.Convert(destField.Type()))
 		return nil
 	}
 
+	// 反向拷贝时将Json反序列化到嵌套消息, map或者slice
+	if impl.reverse && (srcField.Type() == jsonType || srcField.Type() == nullJsonType) {
+		return impl.unmarshalJsonField(destField, srcFieldValue)
+	}
+
+	// null.*类型处理
+	if srcField.IsValid() && isNullType(srcField.Type()) {
+		return impl.setFieldFromNull(destField, srcField)
+	}
+	if isNullType(destField.Type()) {
+		return impl.setNullField(destField, srcField, srcFieldValue)
+	}
+
+	// 目标为指针则分配内存
+	if destField.Kind() == reflect.Ptr {
+		ptr := reflect.New(destField.Type().Elem())
+		if err := impl.setField(ptr.Elem(), srcField, srcFieldValue); err != nil {
+			return err
+		}
+		destField.Set(ptr)
+		return nil
+	}
+
+	// 类型不同的嵌套结构体或结构体slice递归拷贝
+	if isNestedType(destField.Type()) && isNestedSource(srcField.Type()) {
+		return impl.copyNested(destField.Addr().Interface(), srcFieldValue)
+	}
+
+	// 时间转换为Unix秒或者格式化字符串
+	if t, ok := srcFieldValue.(time.Time); ok {
+		return impl.setFieldFromTime(destField, t)
+	}
+
 	// 基础类型快速处理
 	switch destField.Kind() {
 	case reflect.String:
@@ -309,12 +702,19 @@ func (impl *dbCopierImpl) setField(destField reflect.Value, srcField reflect.Val
 			destField.SetString(v)
 			return nil
 		}
+		if isNumberKind(srcField.Kind()) {
+			destField.SetString(fmt.Sprint(srcFieldValue))
+			return nil
+		}
 	case reflect.Int64, reflect.Int: // 将高频的提前
 		if v, ok := impl.tryParseInt64(srcFieldValue); ok {
-			destField.SetInt(v)
+			return impl.setInt(destField, v, srcFieldValue)
 		}
 	case reflect.Float32, reflect.Float64:
 		if num, err := strconv.ParseFloat(fmt.Sprint(srcFieldValue), 64); err == nil {
+			if impl.strict && destField.OverflowFloat(num) {
+				return errFloatOverflow
+			}
 			destField.SetFloat(num)
 			return nil
 		}
@@ -325,11 +725,14 @@ func (impl *dbCopierImpl) setField(destField reflect.Value, srcField reflect.Val
 		}
 	case reflect.Int8, reflect.Int16, reflect.Int32:
 		if v, ok := impl.tryParseInt64(srcFieldValue); ok {
-			destField.SetInt(v)
+			return impl.setInt(destField, v, srcFieldValue)
 		}
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		if v, ok := impl.tryParseUint64(srcFieldValue); ok {
-			destField.SetUint(v)
+			return impl.setUint(destField, v, srcFieldValue)
+		}
+		if v, ok := impl.tryParseInt64(srcFieldValue); ok && v < 0 {
+			return errOverflow // 负数无法转换为无符号整数
 		}
 	}
 
@@ -342,6 +745,35 @@ func (impl *dbCopierImpl) setField(destField reflect.Value, srcField reflect.Val
 	return fmt.Errorf("unsupported type: %s", destField.Kind())
 }
 
+// setFieldFromNull 将null.*类型的值拷贝到目标字段, 无效的null值设置为目标字段的零值
+func (impl *dbCopierImpl) setFieldFromNull(destField reflect.Value, srcField reflect.Value) error {
+	value, valid := unwrapNull(srcField)
+	if !valid {
+		destField.Set(reflect.Zero(destField.Type()))
+		return nil
+	}
+
+	return impl.setField(destField, value, value.Interface())
+}
+
+// setNullField 将值拷贝到null.*类型的目标字段, nil指针或者nil值设置为无效的null值
+func (impl *dbCopierImpl) setNullField(destField reflect.Value, srcField reflect.Value, srcFieldValue any) error {
+	srcField, _ = indirect(srcField)
+	if srcFieldValue == nil || !srcField.IsValid() {
+		destField.Set(reflect.Zero(destField.Type()))
+		return nil
+	}
+
+	value := destField.Field(0)
+	if err := impl.setField(value, srcField, srcField.Interface()); err != nil {
+		return err
+	}
+
+	// null.Time的零值时间视为null, e.g: 空字符串
+	destField.Field(1).SetBool(value.Type() != timeType || !value.Interface().(time.Time).IsZero())
+	return nil
+}
+
 //// nil值处理逻辑
 //func (impl *dbCopierImpl) handleNilValue(field reflect.Value) error {
 //	switch field.Kind() {
@@ -353,6 +785,34 @@ func (impl *dbCopierImpl) setField(destField reflect.Value, srcField reflect.Val
 //	}
 //}
 
+// setInt 设置整数字段, 严格模式下浮点数截断和溢出视为错误
+func (impl *dbCopierImpl) setInt(destField reflect.Value, v int64, srcFieldValue any) error {
+	if impl.strict {
+		if isTruncated(srcFieldValue, float64(v)) {
+			return errTruncated
+		}
+		if destField.OverflowInt(v) {
+			return errOverflow
+		}
+	}
+	destField.SetInt(v)
+	return nil
+}
+
+// setUint 设置无符号整数字段, 严格模式下浮点数截断和溢出视为错误
+func (impl *dbCopierImpl) setUint(destField reflect.Value, v uint64, srcFieldValue any) error {
+	if impl.strict {
+		if isTruncated(srcFieldValue, float64(v)) {
+			return errTruncated
+		}
+		if destField.OverflowUint(v) {
+			return errOverflow
+		}
+	}
+	destField.SetUint(v)
+	return nil
+}
+
 func (impl *dbCopierImpl) tryParseInt64(value any) (int64, bool) {
 	switch v := value.(type) {
 	case float64:
@@ -362,7 +822,7 @@ func (impl *dbCopierImpl) tryParseInt64(value any) (int64, bool) {
 	case int32, int, int64: // 覆盖80%高频类型, 注意: json unmarshal后的数字会是float64l类型
 		return reflect.ValueOf(v).Int(), true
 	case string:
-		if len(v) > 0 && v[0] >= '0' && v[0] <= '9' {
+		if len(v) > 0 && (v[0] >= '0' && v[0] <= '9' || v[0] == '-') {
 			n, err := strconv.ParseInt(v, 10, 64)
 			return n, err == nil
 		}
@@ -385,7 +845,13 @@ func (impl *dbCopierImpl) tryParseUint64(value any) (uint64, bool) {
 	case uint, uint8, uint16, uint32, uint64:
 		return reflect.ValueOf(v).Uint(), true
 	case float32:
-		return uint64(v), true
+		if v >= 0 {
+			return uint64(v), true
+		}
+	case float64:
+		if v >= 0 && !math.IsInf(v, 0) {
+			return uint64(v), true
+		}
 	case string:
 		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
 			return n, true
@@ -418,25 +884,6 @@ func (impl *dbCopierImpl) tryParseNumberFast(value any) (int64, bool) {
 	return 0, false
 }
 
-// 时间类型处理优化
-func (impl *dbCopierImpl) handleTimeField(field reflect.Value, value any) error {
-	switch v := value.(type) {
-	case time.Time:
-		field.Set(reflect.ValueOf(v))
-	case int64:
-		field.Set(reflect.ValueOf(time.Unix(v, 0)))
-	case string:
-		if t, err := time.Parse(time.DateTime, v); err == nil {
-			field.Set(reflect.ValueOf(t))
-		} else {
-			return fmt.Errorf("invalid time format: %w", err)
-		}
-	default:
-		return fmt.Errorf("unsupported time source: %T", value)
-	}
-	return nil
-}
-
 func indirect(reflectValue reflect.Value) (reflect.Value, bool) {
 	for reflectValue.Kind() == reflect.Ptr {
 		return reflectValue.Elem(), true
@@ -479,13 +926,45 @@ func (impl *dbCopierImpl) incrField(destField reflect.Value, srcFieldValue any)
 	return nil
 }
 
-// increaseFieldValue 将数字字段自增
-func (impl *dbCopierImpl) handleJsonField(destField reflect.Value, srcFieldValue any, fn func(...any) []byte) {
-	if isByteSlice(destField) {
-		destField.Set(reflect.ValueOf(types.JSON(fn(srcFieldValue))))
-	} else {
-		destField.Set(reflect.ValueOf(types.JSON(fn())))
+// getFieldName 获取字段的名字, 依次取copier, boil, json tag, tag中的名字原样使用, 都没有则使用snake形式的字段名
+// tag为"-"表示忽略该字段, e.g: `copier:"nickname"`, `copier:"-"`
+func getFieldName(field reflect.StructField) (string, bool) {
+	for _, tagName := range fieldTagNames {
+		tag, exist := field.Tag.Lookup(tagName)
+		if !exist {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		switch name {
+		case "-":
+			return "", false
+		case "":
+			continue
+		default:
+			return name, true
+		}
 	}
+	return format(field.Name), true
+}
+
+// hasTagName 判断字段是否通过tag指定了名字
+func hasTagName(field reflect.StructField) bool {
+	for _, tagName := range fieldTagNames {
+		if name, _, _ := strings.Cut(field.Tag.Get(tagName), ","); name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// toFieldSet 将字段名格式化后转换为集合
+func toFieldSet(fields []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		set[format(field)] = struct{}{}
+	}
+	return set
 }
 
 func format(s string) string {
@@ -511,6 +990,101 @@ func isSupportedType(t reflect.Type) bool {
 	}
 }
 
+// isNumberKind 判断是否是数字类型
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTruncated 判断浮点数源值转换为整数时是否被截断
+func isTruncated(src any, result float64) bool {
+	switch v := src.(type) {
+	case float32:
+		return float64(v) != result
+	case float64:
+		return v != result
+	default:
+		return false
+	}
+}
+
+// isNestedType 判断目标字段是否是可以递归拷贝的结构体或结构体slice
+func isNestedType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+	return t.Kind() == reflect.Struct && t != timeType && !isNullType(t)
+}
+
+// isNestedSource 判断源字段是否可以作为递归拷贝的源对象
+func isNestedSource(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array:
+		return true
+	case reflect.Map:
+		return t == mapType
+	default:
+		return false
+	}
+}
+
+// getAbsentProtoFields 获取protobuf消息中支持presence但未设置的字段, 非protobuf消息返回nil
+func getAbsentProtoFields(src any) map[string]struct{} {
+	msg, ok := src.(proto.Message)
+	if !ok {
+		return nil
+	}
+
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	absentFields := make(map[string]struct{})
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.HasPresence() && !m.Has(fd) {
+			absentFields[format(string(fd.Name()))] = struct{}{}
+		}
+	}
+	return absentFields
+}
+
+// isEqualValue 判断字段值是否相等, 时间类型按时刻比较
+func isEqualValue(a, b any) bool {
+	switch va := a.(type) {
+	case time.Time:
+		if vb, ok := b.(time.Time); ok {
+			return va.Equal(vb)
+		}
+	case null.Time:
+		if vb, ok := b.(null.Time); ok {
+			return va.Valid == vb.Valid && (!va.Valid || va.Time.Equal(vb.Time))
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// isNullType 判断是否为aarondl/null包中的类型, 这些类型的第一个字段为值, 第二个字段Valid表示是否有效
+func isNullType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == nullPkgPath &&
+		t.NumField() == 2 && t.Field(1).Name == "Valid" && t.Field(1).Type.Kind() == reflect.Bool
+}
+
+// unwrapNull 获取null.*类型中的值, 无效时返回false
+func unwrapNull(v reflect.Value) (reflect.Value, bool) {
+	if !v.Field(1).Bool() {
+		return reflect.Value{}, false
+	}
+	return v.Field(0), true
+}
+
 func isByteSlice(v reflect.Value) bool {
 	/// 之前已经有检测/ 需先确保v是有效值
 	//if !v.IsValid() {
@@ -0,0 +1,188 @@
+package sqlboiler
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/types"
+	"github.com/ericlagergren/decimal"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// converterFunc 类型转换函数, 返回值的类型为注册时的目标类型
+type converterFunc func(src reflect.Value) (reflect.Value, error)
+
+type converterKey struct {
+	src  reflect.Type
+	dest reflect.Type
+}
+
+var (
+	converters sync.Map // converterKey => converterFunc
+)
+
+func init() {
+	// timestamppb
+	RegisterConverter(func(src *timestamppb.Timestamp) (time.Time, error) {
+		if src == nil {
+			return time.Time{}, nil
+		}
+		return src.AsTime(), src.CheckValid()
+	})
+	RegisterConverter(func(src time.Time) (*timestamppb.Timestamp, error) {
+		if src.IsZero() {
+			return nil, nil
+		}
+		return timestamppb.New(src), nil
+	})
+
+	// durationpb
+	RegisterConverter(func(src *durationpb.Duration) (time.Duration, error) {
+		if src == nil {
+			return 0, nil
+		}
+		return src.AsDuration(), src.CheckValid()
+	})
+	RegisterConverter(func(src time.Duration) (*durationpb.Duration, error) {
+		return durationpb.New(src), nil
+	})
+
+	// wrapperspb
+	registerWrapperConverter(wrapperspb.String, (*wrapperspb.StringValue).GetValue)
+	registerWrapperConverter(wrapperspb.Bool, (*wrapperspb.BoolValue).GetValue)
+	registerWrapperConverter(wrapperspb.Int32, (*wrapperspb.Int32Value).GetValue)
+	registerWrapperConverter(wrapperspb.Int64, (*wrapperspb.Int64Value).GetValue)
+	registerWrapperConverter(wrapperspb.UInt32, (*wrapperspb.UInt32Value).GetValue)
+	registerWrapperConverter(wrapperspb.UInt64, (*wrapperspb.UInt64Value).GetValue)
+	registerWrapperConverter(wrapperspb.Float, (*wrapperspb.FloatValue).GetValue)
+	registerWrapperConverter(wrapperspb.Double, (*wrapperspb.DoubleValue).GetValue)
+	registerWrapperConverter(wrapperspb.Bytes, (*wrapperspb.BytesValue).GetValue)
+
+	// types.Decimal
+	RegisterConverter(func(src string) (types.Decimal, error) {
+		if src == "" {
+			return types.NewDecimal(new(decimal.Big)), nil
+		}
+		d, err := parseDecimal(src)
+		return types.NewDecimal(d), err
+	})
+	RegisterConverter(func(src string) (types.NullDecimal, error) {
+		if src == "" {
+			return types.NewNullDecimal(nil), nil
+		}
+		d, err := parseDecimal(src)
+		return types.NewNullDecimal(d), err
+	})
+	// 浮点数按最短的十进制表示转换, 避免0.1变成0.1000000000000000055511151231257827021181583404541015625
+	registerDecimalConverter(func(src float64) (*decimal.Big, error) {
+		return parseDecimal(strconv.FormatFloat(src, 'f', -1, 64))
+	})
+	registerDecimalConverter(func(src float32) (*decimal.Big, error) {
+		return parseDecimal(strconv.FormatFloat(float64(src), 'f', -1, 32))
+	})
+	registerDecimalConverter(intToDecimal[int])
+	registerDecimalConverter(intToDecimal[int8])
+	registerDecimalConverter(intToDecimal[int16])
+	registerDecimalConverter(intToDecimal[int32])
+	registerDecimalConverter(intToDecimal[int64])
+	registerDecimalConverter(uintToDecimal[uint])
+	registerDecimalConverter(uintToDecimal[uint8])
+	registerDecimalConverter(uintToDecimal[uint16])
+	registerDecimalConverter(uintToDecimal[uint32])
+	registerDecimalConverter(uintToDecimal[uint64])
+	RegisterConverter(func(src types.Decimal) (string, error) {
+		if src.Big == nil {
+			return "0", nil
+		}
+		return src.String(), nil
+	})
+	RegisterConverter(func(src types.NullDecimal) (string, error) {
+		if src.Big == nil {
+			return "", nil
+		}
+		return src.String(), nil
+	})
+	RegisterConverter(func(src types.Decimal) (float64, error) {
+		if src.Big == nil {
+			return 0, nil
+		}
+		f, _ := src.Float64()
+		return f, nil
+	})
+}
+
+// RegisterConverter 注册源类型S到目标类型D的转换函数, 全局生效, 同样的类型对后注册的覆盖先注册的
+// DbCopier设置字段时优先使用转换函数, 然后才是内置的转换规则, e.g:
+//
+//	RegisterConverter(func(src pb.OrderStatus) (models.OrderStatus, error) {...})
+//
+// 源值为指针解引用得到时, 也会匹配指针类型的转换函数, 例如*timestamppb.Timestamp
+func RegisterConverter[S, D any](fn func(src S) (D, error)) {
+	key := converterKey{src: reflect.TypeFor[S](), dest: reflect.TypeFor[D]()}
+	converters.Store(key, converterFunc(func(src reflect.Value) (reflect.Value, error) {
+		dest, err := fn(src.Interface().(S))
+		if err != nil {
+			return reflect.Value{}, errors.Wrapf(err, "convert %s to %s", key.src, key.dest)
+		}
+		return reflect.ValueOf(&dest).Elem(), nil
+	}))
+}
+
+// registerWrapperConverter 注册wrapperspb类型和对应基础类型之间的双向转换, nil转换为零值
+func registerWrapperConverter[T, W any](wrap func(T) W, unwrap func(W) T) {
+	RegisterConverter(func(src W) (T, error) {
+		return unwrap(src), nil
+	})
+	RegisterConverter(func(src T) (W, error) {
+		return wrap(src), nil
+	})
+}
+
+// registerDecimalConverter 注册数字到types.Decimal和types.NullDecimal的转换
+func registerDecimalConverter[T any](toDecimal func(src T) (*decimal.Big, error)) {
+	RegisterConverter(func(src T) (types.Decimal, error) {
+		d, err := toDecimal(src)
+		return types.NewDecimal(d), err
+	})
+	RegisterConverter(func(src T) (types.NullDecimal, error) {
+		d, err := toDecimal(src)
+		return types.NewNullDecimal(d), err
+	})
+}
+
+func intToDecimal[T int | int8 | int16 | int32 | int64](src T) (*decimal.Big, error) {
+	return new(decimal.Big).SetMantScale(int64(src), 0), nil
+}
+
+func uintToDecimal[T uint | uint8 | uint16 | uint32 | uint64](src T) (*decimal.Big, error) {
+	return new(decimal.Big).SetUint64(uint64(src)), nil
+}
+
+// convertValue 使用注册的转换函数转换源值, 没有匹配的转换函数时返回false
+func convertValue(src reflect.Value, destType reflect.Type) (reflect.Value, bool, error) {
+	if fn, exist := converters.Load(converterKey{src: src.Type(), dest: destType}); exist {
+		v, err := fn.(converterFunc)(src)
+		return v, true, err
+	}
+
+	if src.CanAddr() {
+		if fn, exist := converters.Load(converterKey{src: reflect.PointerTo(src.Type()), dest: destType}); exist {
+			v, err := fn.(converterFunc)(src.Addr())
+			return v, true, err
+		}
+	}
+	return reflect.Value{}, false, nil
+}
+
+func parseDecimal(s string) (*decimal.Big, error) {
+	d, ok := new(decimal.Big).SetString(s)
+	if !ok {
+		return nil, errors.Errorf("invalid decimal: %s", s)
+	}
+	return d, nil
+}
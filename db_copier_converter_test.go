@@ -0,0 +1,85 @@
+package sqlboiler
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/types"
+)
+
+func TestCopyNumberToString(t *testing.T) {
+	type numberRequest struct {
+		Code   int32
+		Amount float64
+		Sn     null.Int64
+	}
+	type stringModel struct {
+		Code   string      `boil:"code"`
+		Amount string      `boil:"amount"`
+		Sn     null.String `boil:"sn"`
+	}
+
+	// 数字转换为十进制字符串, 而不是Go的rune转换
+	var model stringModel
+	if err := newDbCopier().Copy(&model, &numberRequest{Code: 65, Amount: 1.5, Sn: null.Int64From(66)}); err != nil {
+		t.Fatal(err)
+	}
+	if model.Code != "65" || model.Amount != "1.5" || model.Sn.String != "66" {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+}
+
+func TestConvertNumberToDecimal(t *testing.T) {
+	type priceModel struct {
+		Price    types.Decimal     `boil:"price"`
+		Discount types.NullDecimal `boil:"discount"`
+	}
+
+	testCases := []struct {
+		name         string
+		src          any
+		wantPrice    string
+		wantDiscount string
+	}{
+		// json解析的数字都是float64, 不能有二进制浮点数的误差
+		{name: "float64", src: map[string]any{"price": 0.1, "discount": 19.99}, wantPrice: "0.1", wantDiscount: "19.99"},
+		{name: "int", src: map[string]any{"price": 3, "discount": int8(-2)}, wantPrice: "3", wantDiscount: "-2"},
+		{name: "uint64", src: map[string]any{"price": uint64(math.MaxUint64), "discount": uint8(7)}, wantPrice: "18446744073709551615", wantDiscount: "7"},
+		{name: "int32 field", src: &struct {
+			Price    int32
+			Discount float32
+		}{Price: 42, Discount: 0.1}, wantPrice: "42", wantDiscount: "0.1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var model priceModel
+			if err := newDbCopier().Copy(&model, tc.src); err != nil {
+				t.Fatal(err)
+			}
+			if model.Price.String() != tc.wantPrice || model.Discount.String() != tc.wantDiscount {
+				t.Fatalf("got price %s, discount %s", model.Price.String(), model.Discount.String())
+			}
+		})
+	}
+}
+
+func TestConvertEmptyDecimal(t *testing.T) {
+	type decimalModel struct {
+		Price    types.Decimal     `boil:"price"`
+		Discount types.NullDecimal `boil:"discount"`
+	}
+
+	var model decimalModel
+	if err := newDbCopier().Copy(&model, map[string]any{"price": "", "discount": ""}); err != nil {
+		t.Fatal(err)
+	}
+	// 空字符串转换为0, 可空类型转换为无效值
+	if model.Price.Big == nil || model.Price.Big.Sign() != 0 || model.Discount.Big != nil {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+
+	if err := newDbCopier().Copy(&model, map[string]any{"price": "abc"}); err == nil {
+		t.Fatal("invalid decimal should return error")
+	}
+}
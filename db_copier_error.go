@@ -0,0 +1,130 @@
+package sqlboiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FieldError 字段错误, Field为字段路径, e.g: items[1].price, Rule为校验失败的规则, e.g: max
+type FieldError struct {
+	Field string
+	Rule  string
+	Err   error
+}
+
+// CopyError 严格模式下的拷贝错误, 包含所有出错的字段
+type CopyError struct {
+	Fields []*FieldError
+}
+
+// ValidationError 字段校验错误, 包含所有校验失败的字段, 可以序列化为Json返回给客户端
+type ValidationError struct {
+	Fields []*FieldError `json:"fields"`
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON 序列化为{"field": "name", "rule": "max", "message": "..."}
+func (e *FieldError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule,omitempty"`
+		Message string `json:"message"`
+	}{
+		Field:   e.Field,
+		Rule:    e.Rule,
+		Message: e.Err.Error(),
+	})
+}
+
+func (e *CopyError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Error()
+	}
+	return "copy failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		messages[i] = f.Error()
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// addError 记录字段错误, 字段路径加上当前前缀
+func (s *copyState) addError(key string, err error) {
+	s.errs = append(s.errs, &FieldError{Field: s.prefix + key, Err: err})
+}
+
+// fieldError 处理字段错误, 严格模式下收集错误继续拷贝, 否则返回包装后的错误
+func (s *copyState) fieldError(key string, err error, msg string) error {
+	if !s.strict {
+		return errors.Wrapf(err, "%s '%s'", msg, key)
+	}
+
+	// 嵌套拷贝的错误加上字段路径前缀
+	var copyErr *CopyError
+	if errors.As(err, &copyErr) {
+		for _, f := range copyErr.Fields {
+			s.addError(joinFieldPath(key, f.Field), f.Err)
+		}
+		return nil
+	}
+
+	s.addError(key, err)
+	return nil
+}
+
+// addInvalid 记录校验失败的字段
+func (s *copyState) addInvalid(key, rule string, err error) {
+	s.invalid = append(s.invalid, &FieldError{Field: s.prefix + key, Rule: rule, Err: err})
+}
+
+// isInvalid 判断字段是否已经校验失败
+func (s *copyState) isInvalid(key string) bool {
+	return slices.ContainsFunc(s.invalid, func(f *FieldError) bool {
+		return f.Field == s.prefix+key
+	})
+}
+
+// mergeInvalid 合并嵌套拷贝的校验错误, 字段路径加上前缀
+func (s *copyState) mergeInvalid(key string, validationErr *ValidationError) {
+	for _, f := range validationErr.Fields {
+		s.addInvalid(joinFieldPath(key, f.Field), f.Rule, f.Err)
+	}
+}
+
+// invalidErr 返回所有校验失败的字段
+func (s *copyState) invalidErr() error {
+	if len(s.invalid) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: s.invalid}
+}
+
+// err 返回收集到的所有字段错误
+func (s *copyState) err() error {
+	if len(s.errs) == 0 {
+		return nil
+	}
+	return &CopyError{Fields: s.errs}
+}
+
+func joinFieldPath(parent, child string) string {
+	if strings.HasPrefix(child, "[") {
+		return parent + child
+	}
+	return parent + "." + child
+}
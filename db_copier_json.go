@@ -0,0 +1,274 @@
+package sqlboiler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/types"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// JsonSchema Json Schema校验接口, v为encoding/json解码后的值(数字为json.Number),
+// 可以适配第三方的Json Schema实现, e.g: github.com/santhosh-tekuri/jsonschema
+type JsonSchema interface {
+	Validate(v any) error
+}
+
+type jsonFieldKind int
+
+const (
+	jsonFieldObject jsonFieldKind = iota
+	jsonFieldArray
+)
+
+var (
+	protoMessageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+	protoUnmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+	errInvalidJson   = errors.New("invalid json")
+)
+
+// handleJsonField 将源值序列化为Json写入目标字段, 目标字段可以是[]byte, types.JSON, null.JSON或者字符串
+// 源值为nil或者空时写入空的Json数组或对象, 目标为null.JSON时写入null
+func (impl *dbCopierImpl) handleJsonField(destField reflect.Value, key string, srcFieldValue any, kind jsonFieldKind) error {
+	data, err := marshalJsonValue(srcFieldValue)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		if destField.Type() == nullJsonType {
+			destField.Set(reflect.Zero(nullJsonType))
+			return nil
+		}
+		data = kind.empty()
+	}
+
+	if err = kind.check(data); err != nil {
+		return err
+	}
+
+	if schema, exist := impl.jsonSchemas[key]; exist {
+		if err = validateJsonSchema(schema, data); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case destField.Type() == nullJsonType:
+		destField.Set(reflect.ValueOf(null.JSONFrom(data)))
+	case isByteSlice(destField):
+		destField.Set(reflect.ValueOf(data).Convert(destField.Type()))
+	case destField.Kind() == reflect.String:
+		destField.SetString(string(data))
+	default:
+		return fmt.Errorf("unsupported json destination: %s", destField.Type())
+	}
+	return nil
+}
+
+// marshalJsonValue 将源值序列化为Json, protobuf消息使用protojson, 已经编码的字符串和字节数组校验后直接使用
+// 源值为nil或者空时返回nil
+func marshalJsonValue(value any) ([]byte, error) {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case types.JSON:
+		data = v
+	case null.JSON:
+		if !v.Valid {
+			return nil, nil
+		}
+		data = v.JSON
+	case proto.Message:
+		if !v.ProtoReflect().IsValid() {
+			return nil, nil
+		}
+
+		var err error
+		if data, err = protojson.Marshal(v); err != nil {
+			return nil, errors.Wrap(err, "marshal proto json")
+		}
+	default:
+		rv, _ := indirect(reflect.ValueOf(value))
+		if !rv.IsValid() || (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.IsNil() {
+			return nil, nil
+		}
+
+		var err error
+		if data, err = marshalJsonReflect(rv); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) == 0 || string(bytes.TrimSpace(data)) == "null" {
+		return nil, nil
+	}
+
+	if !json.Valid(data) {
+		return nil, errInvalidJson
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// marshalJsonReflect protobuf消息slice的每个元素使用protojson, 其他类型使用encoding/json
+func marshalJsonReflect(rv reflect.Value) ([]byte, error) {
+	if rv.Kind() != reflect.Slice || !rv.Type().Elem().Implements(protoMessageType) {
+		data, err := json.Marshal(rv.Interface())
+		if err != nil {
+			return nil, errors.Wrap(err, "marshal json")
+		}
+		return data, nil
+	}
+
+	items := make([]json.RawMessage, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		data, err := protojson.Marshal(rv.Index(i).Interface().(proto.Message))
+		if err != nil {
+			return nil, errors.Wrapf(err, "marshal proto json at index %d", i)
+		}
+		items[i] = data
+	}
+	return json.Marshal(items)
+}
+
+// validateJsonSchema 使用Schema校验Json内容
+func validateJsonSchema(schema JsonSchema, data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return errors.Wrap(err, "decode json")
+	}
+
+	if err := schema.Validate(v); err != nil {
+		return errors.Wrap(err, "validate json schema")
+	}
+	return nil
+}
+
+func (kind jsonFieldKind) empty() []byte {
+	if kind == jsonFieldArray {
+		return []byte("[]")
+	}
+	return []byte("{}")
+}
+
+// check 检查Json内容是否是对应的数组或者对象
+func (kind jsonFieldKind) check(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case kind == jsonFieldArray && trimmed[0] != '[':
+		return errors.New("json field expects an array")
+	case kind == jsonFieldObject && trimmed[0] != '{':
+		return errors.New("json field expects an object")
+	}
+	return nil
+}
+
+// isJsonField 判断目标字段是否设置为Json数组或Json对象
+func (impl *dbCopierImpl) isJsonField(key string) bool {
+	if _, exist := impl.jsonObjectFields[key]; exist {
+		return true
+	}
+	_, exist := impl.jsonArrayFields[key]
+	return exist
+}
+
+// unmarshalJsonField 将Json反序列化到目标字段, 目标字段可以是protobuf消息, 消息slice, map, slice或者结构体
+// 目标为字符串或者字节数组时直接拷贝, 空的Json或者null设置为零值
+func (impl *dbCopierImpl) unmarshalJsonField(destField reflect.Value, srcFieldValue any) error {
+	data, err := getJsonBytes(srcFieldValue)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 || string(data) == "null" {
+		destField.Set(reflect.Zero(destField.Type()))
+		return nil
+	}
+
+	switch {
+	case destField.Type() == nullJsonType:
+		destField.Set(reflect.ValueOf(null.JSONFrom(data)))
+	case isByteSlice(destField):
+		destField.Set(reflect.ValueOf(append([]byte(nil), data...)).Convert(destField.Type()))
+	case destField.Kind() == reflect.String:
+		destField.SetString(string(data))
+	default:
+		return unmarshalJson(destField, data)
+	}
+	return nil
+}
+
+// unmarshalJson protobuf消息使用protojson反序列化, 其他类型使用encoding/json
+func unmarshalJson(destField reflect.Value, data []byte) error {
+	destType := destField.Type()
+
+	// *pb.Message
+	if destType.Kind() == reflect.Ptr && destType.Implements(protoMessageType) {
+		msg := reflect.New(destType.Elem())
+		if err := protoUnmarshaler.Unmarshal(data, msg.Interface().(proto.Message)); err != nil {
+			return errors.Wrap(err, "unmarshal proto json")
+		}
+		destField.Set(msg)
+		return nil
+	}
+
+	// []*pb.Message
+	if destType.Kind() == reflect.Slice && destType.Elem().Implements(protoMessageType) && destType.Elem().Kind() == reflect.Ptr {
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return errors.Wrap(err, "unmarshal json array")
+		}
+
+		result := reflect.MakeSlice(destType, len(items), len(items))
+		for i, item := range items {
+			msg := reflect.New(destType.Elem().Elem())
+			if err := protoUnmarshaler.Unmarshal(item, msg.Interface().(proto.Message)); err != nil {
+				return errors.Wrapf(err, "unmarshal proto json at index %d", i)
+			}
+			result.Index(i).Set(msg)
+		}
+		destField.Set(result)
+		return nil
+	}
+
+	value := reflect.New(destType)
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return errors.Wrap(err, "unmarshal json")
+	}
+	destField.Set(value.Elem())
+	return nil
+}
+
+// getJsonBytes 获取源值中的Json数据, 无效的null.JSON返回nil
+func getJsonBytes(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case types.JSON:
+		return v, nil
+	case null.JSON:
+		if !v.Valid {
+			return nil, nil
+		}
+		return v.JSON, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, errors.Errorf("unsupported json source: %T", value)
+	}
+}
@@ -0,0 +1,176 @@
+package sqlboiler
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/types"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/apipb"
+)
+
+type jsonModel struct {
+	Profile types.JSON `boil:"profile"`
+	Tags    types.JSON `boil:"tags"`
+	Attrs   null.JSON  `boil:"attrs"`
+	Raw     string     `boil:"raw"`
+}
+
+// jsonSchemaFunc 将函数适配为JsonSchema
+type jsonSchemaFunc func(v any) error
+
+func (f jsonSchemaFunc) Validate(v any) error {
+	return f(v)
+}
+
+// compactJson 去掉Json中的空白, protojson的输出包含随机的空格
+func compactJson(t *testing.T, data []byte) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		t.Fatalf("%s: %v", data, err)
+	}
+	return buf.String()
+}
+
+func TestCopyJsonField(t *testing.T) {
+	type profile struct {
+		Name string `json:"name"`
+		Age  int    `json:"age,omitempty"`
+	}
+
+	testCases := []struct {
+		name      string
+		src       map[string]any
+		wantField func(m *jsonModel) []byte
+		want      string
+	}{
+		{name: "struct", src: map[string]any{"profile": profile{Name: "a"}}, wantField: func(m *jsonModel) []byte { return m.Profile }, want: `{"name":"a"}`},
+		{name: "struct pointer", src: map[string]any{"profile": &profile{Name: "a", Age: 3}}, wantField: func(m *jsonModel) []byte { return m.Profile }, want: `{"name":"a","age":3}`},
+		{name: "map", src: map[string]any{"profile": map[string]any{"b": 1}}, wantField: func(m *jsonModel) []byte { return m.Profile }, want: `{"b":1}`},
+		{name: "proto", src: map[string]any{"profile": &apipb.Api{Name: "n", Version: "v1"}}, wantField: func(m *jsonModel) []byte { return m.Profile }, want: `{"name":"n","version":"v1"}`},
+		{name: "proto slice", src: map[string]any{"tags": []*apipb.Method{{Name: "a"}, {Name: "b"}}}, wantField: func(m *jsonModel) []byte { return m.Tags }, want: `[{"name":"a"},{"name":"b"}]`},
+		{name: "encoded string", src: map[string]any{"profile": ` {"a": 1} `}, wantField: func(m *jsonModel) []byte { return m.Profile }, want: `{"a":1}`},
+		{name: "encoded bytes", src: map[string]any{"tags": []byte(`["x"]`)}, wantField: func(m *jsonModel) []byte { return m.Tags }, want: `["x"]`},
+		{name: "nil object", src: map[string]any{"profile": nil}, wantField: func(m *jsonModel) []byte { return m.Profile }, want: `{}`},
+		{name: "nil slice", src: map[string]any{"tags": []string(nil)}, wantField: func(m *jsonModel) []byte { return m.Tags }, want: `[]`},
+		{name: "null.JSON", src: map[string]any{"attrs": map[string]any{"k": "v"}}, wantField: func(m *jsonModel) []byte { return m.Attrs.JSON }, want: `{"k":"v"}`},
+		{name: "string destination", src: map[string]any{"raw": []string{"a"}}, wantField: func(m *jsonModel) []byte { return []byte(m.Raw) }, want: `["a"]`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var model jsonModel
+			if err := newDbCopier().JSONObject("profile", "attrs").JSONArray("tags", "raw").Copy(&model, tc.src); err != nil {
+				t.Fatal(err)
+			}
+			if got := compactJson(t, tc.wantField(&model)); got != tc.want {
+				t.Fatalf("got %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	// null.JSON的源值为空时写入null, 不会写入空对象
+	model := jsonModel{Attrs: null.JSONFrom([]byte(`{"k":"v"}`))}
+	if err := newDbCopier().JSONObject("attrs").Copy(&model, map[string]any{"attrs": (*apipb.Api)(nil)}); err != nil {
+		t.Fatal(err)
+	}
+	if model.Attrs.Valid {
+		t.Fatalf("expect null json, got: %s", model.Attrs.JSON)
+	}
+}
+
+func TestCopyJsonFieldInvalid(t *testing.T) {
+	testCases := []struct {
+		name    string
+		src     map[string]any
+		wantErr error
+	}{
+		{name: "invalid string", src: map[string]any{"profile": `{"a":`}, wantErr: errInvalidJson},
+		{name: "invalid bytes", src: map[string]any{"tags": []byte(`[1,`)}, wantErr: errInvalidJson},
+		{name: "array for object", src: map[string]any{"profile": `[1]`}},
+		{name: "object for array", src: map[string]any{"tags": map[string]any{"a": 1}}},
+		{name: "unsupported value", src: map[string]any{"profile": func() {}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var model jsonModel
+			err := newDbCopier().JSONObject("profile").JSONArray("tags").Copy(&model, tc.src)
+			if err == nil || tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expect error %v, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCopyJsonSchema(t *testing.T) {
+	errTooOld := errors.New("too old")
+	var got any
+	schema := jsonSchemaFunc(func(v any) error {
+		got = v
+		if age, _ := v.(map[string]any)["age"].(json.Number).Int64(); age > 100 {
+			return errTooOld
+		}
+		return nil
+	})
+
+	// Schema收到的数字为json.Number, 校验失败时不写入目标字段
+	var model jsonModel
+	if err := newDbCopier().JSONObject("profile").JSONSchema("profile", schema).Copy(&model, map[string]any{"profile": map[string]any{"age": 20}}); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := got.(map[string]any); !ok || v["age"] != json.Number("20") || string(model.Profile) != `{"age":20}` {
+		t.Fatalf("unexpected schema value: %#v, profile: %s", got, model.Profile)
+	}
+
+	model = jsonModel{}
+	err := newDbCopier().JSONObject("profile").JSONSchema("profile", schema).Copy(&model, map[string]any{"profile": `{"age":101}`})
+	if !errors.Is(err, errTooOld) || model.Profile != nil {
+		t.Fatalf("expect schema error, got: %v, profile: %s", err, model.Profile)
+	}
+
+	// 没有设置为Json字段时不使用Schema
+	got = nil
+	if err = newDbCopier().JSONSchema("raw", schema).Copy(&model, map[string]any{"raw": `{"age":101}`}); err != nil || got != nil {
+		t.Fatalf("schema used on plain field: %v", err)
+	}
+}
+
+func TestCopyJsonFieldToDTO(t *testing.T) {
+	type jsonResponse struct {
+		Profile *apipb.Api
+		Tags    []*apipb.Method
+		Attrs   map[string]string
+		Raw     []string
+	}
+
+	// protobuf消息和消息slice使用protojson反序列化, 忽略未知字段
+	model := &jsonModel{
+		Profile: types.JSON(`{"name":"n","unknown":1}`),
+		Tags:    types.JSON(`[{"name":"a"},{"name":"b"}]`),
+		Attrs:   null.JSONFrom([]byte(`{"k":"v"}`)),
+		Raw:     `["x"]`,
+	}
+	var resp jsonResponse
+	if err := newDbCopier().JSONArray("raw").CopyToDTO(&resp, model); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Profile.GetName() != "n" || len(resp.Tags) != 2 || resp.Tags[1].GetName() != "b" || resp.Attrs["k"] != "v" || len(resp.Raw) != 1 || resp.Raw[0] != "x" {
+		t.Fatalf("unexpected copy result: %+v", resp)
+	}
+
+	// 空的Json和null设置为零值
+	resp = jsonResponse{}
+	if err := newDbCopier().JSONArray("raw").CopyToDTO(&resp, &jsonModel{Tags: types.JSON(`null`)}); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Profile != nil || resp.Tags != nil || resp.Attrs != nil || resp.Raw != nil {
+		t.Fatalf("unexpected copy result: %+v", resp)
+	}
+
+	if err := newDbCopier().CopyToDTO(&resp, &jsonModel{Profile: types.JSON(`{"name":1}`)}); err == nil {
+		t.Fatal("expect unmarshal error")
+	}
+}
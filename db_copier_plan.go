@@ -0,0 +1,336 @@
+package sqlboiler
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hdget/utils/text"
+)
+
+// copyPlan 编译好的struct到struct的拷贝计划, 按目标字段顺序排列
+type copyPlan struct {
+	fields []planField
+}
+
+type planField struct {
+	srcIndex  []int  // 源字段索引路径, 嵌入结构体和点号路径映射的字段有多级
+	destIndex []int  // 目标字段索引路径
+	srcKey    string // 源字段名, 用于运行时判断字段是否出现
+	destKey   string // 目标字段名
+	destName  string // 目标字段原样的名字, 用于错误路径和返回写入的字段
+	rules     string // 校验规则, 优先使用目标字段的validate tag, 其次是源字段的
+}
+
+type planKey struct {
+	src      reflect.Type
+	dest     reflect.Type
+	matchKey string     // 影响字段匹配的copier选项
+	action   copyAction // 拷贝动作, 决定默认忽略的字段
+	allowKey string     // 本次拷贝允许的字段
+}
+
+// typeInfo 结构体类型的字段信息, 嵌入结构体的字段会被展开
+type typeInfo struct {
+	fields    []fieldInfo
+	key2index map[string][]int  // 字段名到字段索引路径的映射
+	key2name  map[string]string // 字段名到原样的名字的映射
+	rules     map[string]string // 字段名到validate tag的映射
+}
+
+type fieldInfo struct {
+	index     []int  // 字段索引路径
+	key       string // 用于匹配的字段名, 格式化后的name
+	name      string // 原样的名字, 即tag中的名字或者snake形式的字段名
+	matchable bool   // 是否可以参与匹配, 即导出且tag不为"-"
+	supported bool   // 作为源字段时类型是否支持
+	rules     string // validate tag
+}
+
+const (
+	maxFormatCacheSize   = 10000 // map的key可能来自外部输入, 限制缓存大小
+	maxCopyPlanCacheSize = 10000 // 拷贝计划的key包含每次拷贝允许的字段, 限制缓存大小
+)
+
+var (
+	typeInfoCache      sync.Map // reflect.Type => *typeInfo
+	copyPlanCache      sync.Map // planKey => *copyPlan
+	copyPlanCacheCount atomic.Int64
+	formatCache        sync.Map // string => string
+	formatCacheCount   atomic.Int64
+)
+
+// getTypeInfo 获取结构体类型的字段信息, 结果会被缓存
+// 展开嵌入结构体的字段, 和Go的字段提升规则一样层级浅的字段优先
+func getTypeInfo(t reflect.Type) *typeInfo {
+	if v, exist := typeInfoCache.Load(t); exist {
+		return v.(*typeInfo)
+	}
+
+	info := &typeInfo{
+		fields:    make([]fieldInfo, 0, t.NumField()),
+		key2index: make(map[string][]int, t.NumField()),
+		key2name:  make(map[string]string, t.NumField()),
+		rules:     make(map[string]string),
+	}
+
+	type level struct {
+		t     reflect.Type
+		index []int
+	}
+	key2pos := make(map[string]int) // 字段名在fields中的位置
+	visited := map[reflect.Type]struct{}{t: {}}
+	current := []level{{t: t}}
+	for len(current) > 0 {
+		var next []level
+		for _, l := range current {
+			for i := 0; i < l.t.NumField(); i++ {
+				field := l.t.Field(i)
+				index := append(slices.Clone(l.index), i)
+
+				if embeddedType, ok := getEmbeddedStruct(field); ok {
+					if _, exist := visited[embeddedType]; !exist {
+						visited[embeddedType] = struct{}{}
+						next = append(next, level{t: embeddedType, index: index})
+					}
+					continue
+				}
+
+				// 匹配时使用格式化后的名字, 这样map的key, 没有tag的字段名和tag中的名字可以互相匹配
+				name, ok := getFieldName(field)
+				key := format(name)
+				f := fieldInfo{
+					index:     index,
+					key:       key,
+					name:      name,
+					matchable: ok && text.IsCapitalized(field.Name),
+					supported: isSupportedType(field.Type),
+					rules:     field.Tag.Get(validateTagName),
+				}
+				if !f.matchable {
+					info.fields = append(info.fields, f)
+					continue
+				}
+
+				// 同一层级同名时后面的覆盖前面的, 层级更深的同名字段忽略
+				if pos, exist := key2pos[key]; exist {
+					if len(info.fields[pos].index) < len(index) {
+						continue
+					}
+					info.fields[pos] = f
+				} else {
+					key2pos[key] = len(info.fields)
+					info.fields = append(info.fields, f)
+				}
+				info.key2index[key] = index
+				info.key2name[key] = name
+				if f.rules != "" {
+					info.rules[key] = f.rules
+				} else {
+					delete(info.rules, key)
+				}
+			}
+		}
+		current = next
+	}
+
+	v, _ := typeInfoCache.LoadOrStore(t, info)
+	return v.(*typeInfo)
+}
+
+// getEmbeddedStruct 判断字段是否是需要展开的嵌入结构体, 有tag名字的嵌入字段作为普通字段处理
+func getEmbeddedStruct(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous || hasTagName(field) {
+		return nil, false
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType || isNullType(t) {
+		return nil, false
+	}
+	return t, true
+}
+
+// getFieldPath 获取点号路径对应的字段索引路径, e.g: address.city, 中间字段可以是结构体指针
+func getFieldPath(t reflect.Type, path string) ([]int, bool) {
+	var index []int
+	for _, key := range strings.Split(path, ".") {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, false
+		}
+
+		fieldIndex, exist := getTypeInfo(t).key2index[key]
+		if !exist {
+			return nil, false
+		}
+		index = append(index, fieldIndex...)
+		t = t.FieldByIndex(fieldIndex).Type
+	}
+	return index, true
+}
+
+// fieldByIndex 按索引路径获取字段, 路径中的指针为nil时返回无效值
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// fieldByIndexAlloc 按索引路径获取字段, 路径中的指针为nil时分配内存, 无法分配时(如未导出的嵌入指针)返回无效值
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// getCopyPlan 获取struct到struct的拷贝计划, 按(源类型, 目标类型, 选项)缓存, 最多缓存maxCopyPlanCacheSize个
+func (impl *dbCopierImpl) getCopyPlan(fromType, toType reflect.Type, state *copyState) *copyPlan {
+	key := planKey{src: fromType, dest: toType, matchKey: impl.matchKey, action: state.action, allowKey: state.allowKey}
+	if v, exist := copyPlanCache.Load(key); exist {
+		return v.(*copyPlan)
+	}
+
+	// 收集需要拷贝的字段, 同名时后面的覆盖前面的
+	srcFields := make(map[string]fieldInfo)
+	for _, f := range getTypeInfo(fromType).fields {
+		if !f.matchable || !f.supported {
+			continue
+		}
+
+		destKey := impl.mapKey(f.key)
+		if impl.skip(destKey, state) {
+			continue
+		}
+		srcFields[destKey] = f
+	}
+
+	// 点号路径映射优先于同名字段, e.g: address.city => city
+	for srcPath, destKey := range impl.fieldMappings {
+		if !strings.Contains(srcPath, ".") || impl.skip(destKey, state) {
+			continue
+		}
+
+		if index, ok := getFieldPath(fromType, srcPath); ok {
+			rootKey, _, _ := strings.Cut(srcPath, ".")
+			srcFields[destKey] = fieldInfo{index: index, key: rootKey}
+		}
+	}
+
+	plan := &copyPlan{fields: make([]planField, 0, len(srcFields))}
+	for _, f := range getTypeInfo(toType).fields {
+		if !f.matchable {
+			continue
+		}
+
+		if srcField, exist := srcFields[f.key]; exist {
+			rules := f.rules
+			if rules == "" {
+				rules = srcField.rules
+			}
+
+			plan.fields = append(plan.fields, planField{
+				srcIndex:  srcField.index,
+				destIndex: f.index,
+				srcKey:    srcField.key,
+				destKey:   f.key,
+				destName:  f.name,
+				rules:     rules,
+			})
+		}
+	}
+
+	// 缓存满了以后不再缓存新的拷贝计划, 每次重新生成
+	if copyPlanCacheCount.Load() >= maxCopyPlanCacheSize {
+		return plan
+	}
+
+	v, loaded := copyPlanCache.LoadOrStore(key, plan)
+	if !loaded {
+		copyPlanCacheCount.Add(1)
+	}
+	return v.(*copyPlan)
+}
+
+// updateMatchKey 重新生成影响字段匹配的选项的标识, 在修改选项时调用, 避免每次拷贝都排序拼接
+func (impl *dbCopierImpl) updateMatchKey() {
+	var builder strings.Builder
+	if impl.profile != nil {
+		builder.WriteString("p:")
+		builder.WriteString(strconv.FormatUint(impl.profile.id, 10))
+		builder.WriteString(";")
+	}
+
+	writeSet := func(prefix string, set map[string]struct{}) {
+		builder.WriteString(prefix)
+		builder.WriteString(strings.Join(slices.Sorted(maps.Keys(set)), ","))
+		builder.WriteString(";")
+	}
+	writeSet("w:", impl.whitelistFields)
+	writeSet("b:", impl.blacklistFields)
+
+	mappings := make([]string, 0, len(impl.fieldMappings))
+	for src, dest := range impl.fieldMappings {
+		mappings = append(mappings, src+"="+dest)
+	}
+	slices.Sort(mappings)
+	builder.WriteString("m:")
+	builder.WriteString(strings.Join(mappings, ","))
+	impl.matchKey = builder.String()
+}
+
+// joinFields 将字段名格式化排序后拼接, 用于区分拷贝计划
+func joinFields(fields []string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	formatted := make([]string, len(fields))
+	for i, field := range fields {
+		formatted[i] = format(field)
+	}
+	slices.Sort(formatted)
+	return strings.Join(formatted, ",")
+}
+
+// cachedFormat 带缓存的format, 用于map的key
+func cachedFormat(s string) string {
+	if v, exist := formatCache.Load(s); exist {
+		return v.(string)
+	}
+
+	formatted := format(s)
+	if formatCacheCount.Load() < maxFormatCacheSize {
+		if _, loaded := formatCache.LoadOrStore(s, formatted); !loaded {
+			formatCacheCount.Add(1)
+		}
+	}
+	return formatted
+}
@@ -0,0 +1,228 @@
+package sqlboiler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aarondl/null/v8"
+)
+
+type benchRequest struct {
+	Id       int64
+	Name     string
+	Nickname *string
+	Age      int32
+	Email    string
+	Score    float64
+	Status   int
+	Remark   string
+	Birthday time.Time
+	Internal string `copier:"-"`
+}
+
+type benchModel struct {
+	ID        int64       `boil:"id" json:"id"`
+	Name      string      `boil:"name" json:"name"`
+	Nickname  null.String `boil:"nickname" json:"nickname,omitempty"`
+	Age       int         `boil:"age" json:"age"`
+	Email     string      `boil:"email" json:"email"`
+	Score     float64     `boil:"score" json:"score"`
+	Status    int8        `boil:"status" json:"status"`
+	Remark    string      `boil:"remark" json:"remark"`
+	Birthday  time.Time   `boil:"birthday" json:"birthday"`
+	CreatedAt time.Time   `boil:"created_at" json:"created_at"`
+	UpdatedAt time.Time   `boil:"updated_at" json:"updated_at"`
+	Version   int         `boil:"version" json:"version"`
+}
+
+// resetCopyCaches 清空类型信息, 拷贝计划和字段名缓存, 模拟没有缓存时每次拷贝都需要反射解析类型
+func resetCopyCaches() {
+	typeInfoCache.Clear()
+	copyPlanCache.Clear()
+	copyPlanCacheCount.Store(0)
+	formatCache.Clear()
+	formatCacheCount.Store(0)
+}
+
+func newBenchRequest() *benchRequest {
+	nickname := "bob"
+	return &benchRequest{
+		Id:       1,
+		Name:     "robert",
+		Nickname: &nickname,
+		Age:      30,
+		Email:    "bob@example.com",
+		Score:    98.5,
+		Status:   1,
+		Remark:   "vip",
+		Birthday: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func newBenchMap() map[string]any {
+	return map[string]any{
+		"id":       float64(1),
+		"name":     "robert",
+		"nickname": "bob",
+		"age":      float64(30),
+		"email":    "bob@example.com",
+		"score":    98.5,
+		"status":   float64(1),
+		"remark":   "vip",
+		"birthday": "1990-01-02 00:00:00",
+	}
+}
+
+func BenchmarkCopyStructToStruct(b *testing.B) {
+	src := newBenchRequest()
+	copier := newDbCopier()
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var dest benchModel
+			if err := copier.CopyForCreate(&dest, src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			resetCopyCaches()
+			var dest benchModel
+			if err := copier.CopyForCreate(&dest, src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkCopyMapToStruct(b *testing.B) {
+	src := newBenchMap()
+	copier := newDbCopier()
+
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var dest benchModel
+			if err := copier.CopyForCreate(&dest, src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			resetCopyCaches()
+			var dest benchModel
+			if err := copier.CopyForCreate(&dest, src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkCopySliceToSlice(b *testing.B) {
+	src := make([]*benchRequest, 100)
+	for i := range src {
+		src[i] = newBenchRequest()
+	}
+	copier := newDbCopier()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var dest []*benchModel
+		if err := copier.CopyForCreate(&dest, src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCopyPlanCacheKey(t *testing.T) {
+	src := newBenchRequest()
+
+	var created benchModel
+	if err := newDbCopier().CopyForCreate(&created, src); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != 1 || created.Name != "robert" || created.Nickname.String != "bob" || created.Age != 30 {
+		t.Fatalf("unexpected copy result: %+v", created)
+	}
+
+	// 同样的类型, 不同的选项不能共用拷贝计划
+	var whitelisted benchModel
+	if err := newDbCopier().Whitelist("name").CopyForCreate(&whitelisted, src); err != nil {
+		t.Fatal(err)
+	}
+	if whitelisted.ID != 0 || whitelisted.Name != "robert" {
+		t.Fatalf("whitelist ignored: %+v", whitelisted)
+	}
+
+	var mapped benchModel
+	if err := newDbCopier().Map("remark", "email").Blacklist("remark").Copy(&mapped, src); err != nil {
+		t.Fatal(err)
+	}
+	if mapped.Email != "vip" || mapped.Remark != "" {
+		t.Fatalf("mapping ignored: %+v", mapped)
+	}
+
+	var edited benchModel
+	cols, err := newDbCopier().CopyForEditColumns(&edited, src, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.ID != 1 || len(cols) != 9 {
+		t.Fatalf("allow fields ignored: %+v %v", edited, cols)
+	}
+
+	edited = benchModel{}
+	cols, err = newDbCopier().CopyForEditColumns(&edited, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.ID != 0 || len(cols) != 8 {
+		t.Fatalf("edit skip fields ignored: %+v %v", edited, cols)
+	}
+}
+
+func TestCopyPlanCacheLimit(t *testing.T) {
+	resetCopyCaches()
+	t.Cleanup(resetCopyCaches)
+
+	countPlans := func() int {
+		n := 0
+		copyPlanCache.Range(func(any, any) bool {
+			n++
+			return true
+		})
+		return n
+	}
+
+	// 模拟只剩一个位置的缓存
+	copyPlanCacheCount.Store(maxCopyPlanCacheSize - 1)
+	src := newBenchRequest()
+	var model benchModel
+	if err := newDbCopier().Whitelist("name").Copy(&model, src); err != nil {
+		t.Fatal(err)
+	}
+	if countPlans() != 1 || copyPlanCacheCount.Load() != maxCopyPlanCacheSize {
+		t.Fatalf("plan not cached: %d", countPlans())
+	}
+
+	// 缓存满了以后仍然可以拷贝, 但是不再缓存新的计划
+	for _, field := range []string{"email", "remark", "age"} {
+		model = benchModel{}
+		if err := newDbCopier().Whitelist(field).Copy(&model, src); err != nil {
+			t.Fatal(err)
+		}
+		if model.Name != "" || model.Email == "" && model.Remark == "" && model.Age == 0 {
+			t.Fatalf("whitelist %s ignored: %+v", field, model)
+		}
+	}
+	if countPlans() != 1 || copyPlanCacheCount.Load() != maxCopyPlanCacheSize {
+		t.Fatalf("plan cache exceeds limit: %d", countPlans())
+	}
+}
@@ -0,0 +1,116 @@
+package sqlboiler
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// ValueProvider 计算字段值的函数, 返回nil表示不设置该字段
+type ValueProvider func() (any, error)
+
+type copyAction int
+
+const (
+	copyActionNone   copyAction = iota // Copy/CopyToDTO, 不执行默认值和计算字段
+	copyActionCreate                   // CopyForCreate
+	copyActionEdit                     // CopyForEdit/CopyForEditColumns
+)
+
+type fieldProvider struct {
+	provider ValueProvider
+	onEdit   bool // 编辑时是否执行
+}
+
+// Default 设置字段的默认值, CopyForCreate/CopyForEdit拷贝用户数据后字段仍为零值时使用, e.g:
+//
+//	copier.Default("status", "draft")
+func (impl *dbCopierImpl) Default(field string, value any) DbCopier {
+	impl.defaults[format(field)] = value
+	return impl
+}
+
+// Provide 设置计算字段, CopyForCreate/CopyForEdit拷贝用户数据后总是使用provider的返回值, 不受黑名单和白名单影响, e.g:
+//
+//	copier.Provide("updated_by", func() (any, error) { return ctx.Uid(), nil })
+func (impl *dbCopierImpl) Provide(field string, provider ValueProvider) DbCopier {
+	impl.providers[format(field)] = fieldProvider{provider: provider, onEdit: true}
+	return impl
+}
+
+// ProvideOnCreate 设置只在CopyForCreate时执行的计算字段, e.g:
+//
+//	copier.ProvideOnCreate("sn", func() (any, error) { return idGenerator.Next() })
+//	copier.ProvideOnCreate("created_by", func() (any, error) { return ctx.Uid(), nil })
+func (impl *dbCopierImpl) ProvideOnCreate(field string, provider ValueProvider) DbCopier {
+	impl.providers[format(field)] = fieldProvider{provider: provider}
+	return impl
+}
+
+// applyProviders 在拷贝用户数据后设置计算字段和默认值, 目标对象中不存在的字段忽略
+func (impl *dbCopierImpl) applyProviders(to reflect.Value, state *copyState) error {
+	if state.action == copyActionNone || len(impl.providers) == 0 && len(impl.defaults) == 0 {
+		return nil
+	}
+
+	info := getTypeInfo(to.Type())
+	for _, key := range slices.Sorted(maps.Keys(impl.providers)) {
+		p := impl.providers[key]
+		if state.action == copyActionEdit && !p.onEdit {
+			continue
+		}
+
+		index, exist := info.key2index[key]
+		if !exist {
+			continue
+		}
+
+		value, err := p.provider()
+		if err != nil {
+			if err = state.fieldError(info.key2name[key], err, "provide field"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = impl.setProvidedField(fieldByIndexAlloc(to, index), info.key2name[key], value, state); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(impl.defaults)) {
+		index, exist := info.key2index[key]
+		if !exist {
+			continue
+		}
+
+		destField := fieldByIndexAlloc(to, index)
+		if !destField.IsValid() || !destField.IsZero() {
+			continue
+		}
+
+		if err := impl.setProvidedField(destField, info.key2name[key], impl.defaults[key], state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setProvidedField 设置计算字段或默认值, 和用户数据一样进行类型转换并记录写入的字段
+func (impl *dbCopierImpl) setProvidedField(destField reflect.Value, name string, value any, state *copyState) error {
+	if value == nil || !destField.IsValid() || !destField.CanSet() {
+		return nil
+	}
+
+	var oldValue any
+	if state.onlyChanged {
+		oldValue = destField.Interface()
+	}
+
+	if err := impl.setField(destField, reflect.ValueOf(value), value); err != nil {
+		return state.fieldError(name, err, "provide field")
+	}
+
+	state.track(name, oldValue, destField)
+	return nil
+}
@@ -0,0 +1,573 @@
+package sqlboiler
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/types"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type isolationModel struct {
+	ID      int64  `boil:"id"`
+	Tid     int64  `boil:"tid"`
+	Version int    `boil:"version"`
+	Name    string `boil:"name"`
+	Remark  string `boil:"remark"`
+	Counter int    `boil:"counter"`
+}
+
+// profileFields 获取配置中的字段集合, 用于比较配置是否被修改
+func profileFields(p *copierProfile) [3][]string {
+	return [3][]string{
+		slices.Sorted(maps.Keys(p.createSkipFields)),
+		slices.Sorted(maps.Keys(p.editSkipFields)),
+		slices.Sorted(maps.Keys(p.autoIncrFields)),
+	}
+}
+
+// TestCopierConcurrentIsolation 在go test -race下验证并发拷贝时选项互不影响, 且不会修改默认配置
+func TestCopierConcurrentIsolation(t *testing.T) {
+	profile := currentCopierProfile.Load()
+	before := profileFields(profile)
+	defaults := DefaultCopierProfile()
+
+	shared := newDbCopier()
+	src := map[string]any{"id": 1, "tid": 2, "version": 3, "name": "n", "remark": "r", "counter": 5}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// 允许的字段只对本次拷贝生效
+				var created isolationModel
+				if err := shared.CopyForCreate(&created, src, "tid"); err != nil {
+					t.Error(err)
+					return
+				}
+				if created.Tid != 2 || created.Version != 0 || created.ID != 1 {
+					t.Errorf("create with allowed tid: %+v", created)
+					return
+				}
+
+				created = isolationModel{}
+				if err := shared.CopyForCreate(&created, src); err != nil {
+					t.Error(err)
+					return
+				}
+				if created.Tid != 0 {
+					t.Errorf("allowed field leaked to later copy: %+v", created)
+					return
+				}
+
+				var edited isolationModel
+				cols, err := shared.CopyForEditColumns(&edited, src, "version")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if edited.ID != 0 || edited.Version != 4 || slices.Contains(cols, "id") {
+					t.Errorf("edit with allowed version: %+v %v", edited, cols)
+					return
+				}
+
+				// 每个goroutine的copier各自设置自增字段和黑名单
+				own := newDbCopier().AutoIncr(fmt.Sprintf("counter%d", i%2)).Blacklist("remark")
+				if i%2 == 0 {
+					own.AutoIncr("counter")
+				}
+				edited = isolationModel{}
+				if err = own.CopyForEdit(&edited, src); err != nil {
+					t.Error(err)
+					return
+				}
+				wantCounter := 5
+				if i%2 == 0 {
+					wantCounter = 6
+				}
+				if edited.Counter != wantCounter || edited.Remark != "" || edited.Name != "n" {
+					t.Errorf("copier options leaked between copiers: %+v", edited)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if currentCopierProfile.Load() != profile {
+		t.Fatal("copier profile replaced by copies")
+	}
+	if after := profileFields(profile); fmt.Sprint(after) != fmt.Sprint(before) {
+		t.Fatalf("copier profile modified, before: %v, after: %v", before, after)
+	}
+	if fmt.Sprint(DefaultCopierProfile()) != fmt.Sprint(defaults) {
+		t.Fatal("default copier profile modified")
+	}
+
+	// 新建的copier仍然使用默认配置
+	var created isolationModel
+	if err := newDbCopier().CopyForCreate(&created, src); err != nil {
+		t.Fatal(err)
+	}
+	if created.Tid != 0 || created.Version != 0 || created.Counter != 5 {
+		t.Fatalf("new copier does not use default profile: %+v", created)
+	}
+}
+
+func TestSetCopierProfile(t *testing.T) {
+	t.Cleanup(func() { SetCopierProfile(defaultCopierProfile) })
+
+	existing := newDbCopier()
+
+	profile := DefaultCopierProfile()
+	profile.CreateSkipFields = append(profile.CreateSkipFields, "remark")
+	profile.AutoIncrFields = append(profile.AutoIncrFields, "counter")
+	SetCopierProfile(profile)
+
+	src := map[string]any{"name": "n", "remark": "r", "counter": 1}
+
+	var created isolationModel
+	if err := newDbCopier().CopyForCreate(&created, src); err != nil {
+		t.Fatal(err)
+	}
+	if created.Remark != "" || created.Counter != 2 {
+		t.Fatalf("profile not applied to new copier: %+v", created)
+	}
+
+	// 已经创建的copier不受影响
+	created = isolationModel{}
+	if err := existing.CopyForCreate(&created, src); err != nil {
+		t.Fatal(err)
+	}
+	if created.Remark != "r" || created.Counter != 1 {
+		t.Fatalf("profile applied to existing copier: %+v", created)
+	}
+}
+
+func TestCopySlice(t *testing.T) {
+	src := []*benchRequest{newBenchRequest(), newBenchRequest()}
+	src[1].Id = 2
+
+	var dest []*benchModel
+	if err := newDbCopier().CopyForCreate(&dest, src); err != nil {
+		t.Fatal(err)
+	}
+	if len(dest) != 2 || dest[0].ID != 1 || dest[1].ID != 2 {
+		t.Fatalf("unexpected slice copy result: %+v", dest)
+	}
+
+	// 源元素为nil时返回带下标的错误, 而不是在目标slice中留下nil元素
+	src = append(src, nil)
+	dest = nil
+	err := newDbCopier().CopyForCreate(&dest, src)
+	if !errors.Is(err, errNilElement) || !strings.Contains(err.Error(), "index 2") {
+		t.Fatalf("expect nil element error at index 2, got: %v", err)
+	}
+	if dest != nil {
+		t.Fatalf("dest modified on error: %+v", dest)
+	}
+}
+
+func TestCopyNilSource(t *testing.T) {
+	type editRequest struct {
+		Name *string
+		Nick *string
+		Age  *int32
+		Code *string
+	}
+	type editModel struct {
+		Name string      `boil:"name"`
+		Nick null.String `boil:"nick"`
+		Age  int         `boil:"age"`
+		Code string      `boil:"code"`
+	}
+
+	empty := ""
+	model := editModel{Name: "old", Nick: null.StringFrom("old"), Age: 5, Code: "old"}
+	cols, err := newDbCopier().CopyForEditColumns(&model, &editRequest{Code: &empty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// nil指针只将null.*类型的字段设置为无效值, 其他字段保持不变且不返回
+	if model.Name != "old" || model.Age != 5 || model.Nick.Valid || model.Code != "" {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+	if fmt.Sprint(cols) != "[nick code]" {
+		t.Fatalf("unexpected columns: %v", cols)
+	}
+
+	model = editModel{Name: "old", Nick: null.StringFrom("old"), Age: 5}
+	cols, err = newDbCopier().CopyForEditColumns(&model, map[string]any{"name": nil, "nick": nil, "age": 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Name != "old" || model.Nick.Valid || model.Age != 6 {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+	if slices.Contains(cols, "name") || !slices.Contains(cols, "nick") {
+		t.Fatalf("unexpected columns: %v", cols)
+	}
+}
+
+func TestCopyFieldTags(t *testing.T) {
+	type tagRequest struct {
+		Nick     string `copier:"nickname" json:"nick"`
+		IpV4     string `json:"ip_v4"`
+		Password string `copier:"-" json:"password"`
+		Street   string
+	}
+	type tagModel struct {
+		Nickname string `boil:"nickname"`
+		IpV4     int    `boil:"ip_v4" json:"ipv4"`
+		Password string `boil:"password"`
+		Address  string `boil:"address"`
+	}
+
+	// copier tag优先于json tag, copier:"-"的字段忽略, Map用于无法修改tag的字段
+	var model tagModel
+	src := &tagRequest{Nick: "n", IpV4: "4", Password: "secret", Street: "s"}
+	if err := newDbCopier().Map("street", "address").Copy(&model, src); err != nil {
+		t.Fatal(err)
+	}
+	if model.Nickname != "n" || model.IpV4 != 4 || model.Password != "" || model.Address != "s" {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+
+	// tag中的名字原样用于错误路径, 不会转换为ip_v_4
+	src.IpV4 = "x"
+	err := newDbCopier().Strict().Copy(&model, src)
+	var copyErr *CopyError
+	if !errors.As(err, &copyErr) || len(copyErr.Fields) != 1 || copyErr.Fields[0].Field != "ip_v4" {
+		t.Fatalf("expect error on ip_v4, got: %v", err)
+	}
+
+	// map的key不区分命名风格
+	model = tagModel{}
+	if err = newDbCopier().Copy(&model, map[string]any{"Nickname": "m", "ipV4": 6, "IP_V4": 6}); err != nil {
+		t.Fatal(err)
+	}
+	if model.Nickname != "m" || model.IpV4 != 6 {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+}
+
+func TestCopyForEditColumnNames(t *testing.T) {
+	type addressRequest struct {
+		AddressLine1 string `json:"address_line1"`
+		Sku2         string
+		Remark       string
+	}
+	type addressModel struct {
+		AddressLine1 string `boil:"address_line1"`
+		Sku2         string `boil:"sku2"`
+		Remark       string
+		UpdatedBy    string `boil:"updated_by2"`
+	}
+
+	// 返回原样的列名, 结尾为数字的列名不能转换为address_line_1
+	var model addressModel
+	cols, err := newDbCopier().Provide("updated_by2", func() (any, error) { return "u", nil }).
+		CopyForEditColumns(&model, &addressRequest{AddressLine1: "a", Sku2: "s", Remark: "r"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(cols) != "[address_line1 sku2 remark updated_by2]" {
+		t.Fatalf("unexpected columns: %v", cols)
+	}
+
+	model = addressModel{}
+	cols, err = newDbCopier().CopyForEditColumns(&model, map[string]any{"addressLine1": "a", "sku2": "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.AddressLine1 != "a" || model.Sku2 != "s" || fmt.Sprint(slices.Sorted(slices.Values(cols))) != "[address_line1 sku2]" {
+		t.Fatalf("unexpected copy result: %+v %v", model, cols)
+	}
+}
+
+type apiModel struct {
+	Name          string      `boil:"name"`
+	SourceContext null.String `boil:"source_context"`
+}
+
+func TestCopyFieldMask(t *testing.T) {
+	src := &apipb.Api{Name: "n"}
+
+	// 只拷贝FieldMask中的字段, 嵌套路径取第一级
+	model := apiModel{Name: "old", SourceContext: null.StringFrom("old")}
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"source_context.file_name"}}
+	cols, err := newDbCopier().FieldMask(mask).CopyForEditColumns(&model, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Name != "old" || model.SourceContext.Valid || fmt.Sprint(cols) != "[source_context]" {
+		t.Fatalf("unexpected copy result: %+v %v", model, cols)
+	}
+
+	// 同样适用于map源对象
+	model = apiModel{SourceContext: null.StringFrom("old")}
+	mask = &fieldmaskpb.FieldMask{Paths: []string{"name"}}
+	if err = newDbCopier().FieldMask(mask).Copy(&model, map[string]any{"name": "n", "source_context": "new"}); err != nil {
+		t.Fatal(err)
+	}
+	if model.Name != "n" || model.SourceContext.String != "old" {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+
+	// 客户端没有发送FieldMask时不限制字段
+	for _, mask := range []*fieldmaskpb.FieldMask{nil, {}} {
+		model = apiModel{SourceContext: null.StringFrom("old")}
+		if err = newDbCopier().FieldMask(mask).Copy(&model, src); err != nil {
+			t.Fatal(err)
+		}
+		if model.Name != "n" || model.SourceContext.Valid {
+			t.Fatalf("mask %v limited copy: %+v", mask, model)
+		}
+	}
+}
+
+func TestCopyPresence(t *testing.T) {
+	src := &apipb.Api{Name: "n"}
+
+	// 没有设置的message字段视为客户端没有发送, 不会清空已有的值
+	model := apiModel{SourceContext: null.StringFrom("old")}
+	cols, err := newDbCopier().Presence().CopyForEditColumns(&model, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.SourceContext.String != "old" || slices.Contains(cols, "source_context") {
+		t.Fatalf("absent field copied: %+v %v", model, cols)
+	}
+
+	// 不使用Presence时nil视为客户端发送了空值
+	cols, err = newDbCopier().CopyForEditColumns(&model, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.SourceContext.Valid || !slices.Contains(cols, "source_context") {
+		t.Fatalf("nil field not copied: %+v %v", model, cols)
+	}
+}
+
+type strictItem struct {
+	Qty int8 `boil:"qty"`
+}
+
+type strictModel struct {
+	ID    int64        `boil:"id"`
+	Age   int8         `boil:"age"`
+	Count uint         `boil:"count"`
+	Level uint8        `boil:"level"`
+	Price float32      `boil:"price"`
+	Items []strictItem `boil:"items"`
+}
+
+func TestCopyStrict(t *testing.T) {
+	src := map[string]any{"id": 3.7, "age": "-5", "count": float64(2), "unknown": 1}
+
+	// 非严格模式下忽略未知字段, 浮点数截断为整数
+	var model strictModel
+	if err := newDbCopier().Copy(&model, src); err != nil {
+		t.Fatal(err)
+	}
+	if model.ID != 3 || model.Age != -5 || model.Count != 2 {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+
+	testCases := []struct {
+		name    string
+		src     map[string]any
+		wantErr error
+	}{
+		{name: "unknown field", src: map[string]any{"unknown": 1}, wantErr: errUnknownField},
+		{name: "float truncated", src: map[string]any{"id": 3.7}, wantErr: errTruncated},
+		{name: "int8 overflow", src: map[string]any{"age": 300}, wantErr: errOverflow},
+		{name: "int8 overflow from string", src: map[string]any{"age": "-129"}, wantErr: errOverflow},
+		{name: "negative uint", src: map[string]any{"count": -1}, wantErr: errOverflow},
+		{name: "uint8 overflow", src: map[string]any{"level": uint(256)}, wantErr: errOverflow},
+		{name: "float32 overflow", src: map[string]any{"price": 1e300}, wantErr: errFloatOverflow},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var copyErr *CopyError
+			err := newDbCopier().Strict().Copy(&strictModel{}, tc.src)
+			if !errors.As(err, &copyErr) || len(copyErr.Fields) != 1 || !errors.Is(copyErr.Fields[0], tc.wantErr) {
+				t.Fatalf("expect %v, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	// 无损转换在严格模式下正常拷贝
+	model = strictModel{}
+	if err := newDbCopier().Strict().Copy(&model, map[string]any{"id": float64(3), "age": int64(-128), "count": "7", "level": 255, "price": 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	if model.ID != 3 || model.Age != -128 || model.Count != 7 || model.Level != 255 || model.Price != 1.5 {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+}
+
+func TestCopyStrictErrors(t *testing.T) {
+	// 拷贝不会在第一个错误处停止, 嵌套字段的错误带上字段路径
+	var model strictModel
+	err := newDbCopier().Strict().Copy(&model, map[string]any{
+		"id":    3.7,
+		"age":   300,
+		"price": 1.5,
+		"items": []any{map[string]any{"qty": 1}, map[string]any{"qty": 1000, "extra": 1}},
+	})
+	var copyErr *CopyError
+	if !errors.As(err, &copyErr) {
+		t.Fatalf("expect copy error, got: %v", err)
+	}
+	got := make(map[string]error)
+	for _, f := range copyErr.Fields {
+		got[f.Field] = f.Err
+	}
+	want := map[string]error{"id": errTruncated, "age": errOverflow, "items[1].qty": errOverflow, "items[1].extra": errUnknownField}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected error fields: %v", err)
+	}
+	for field, wantErr := range want {
+		if !errors.Is(got[field], wantErr) {
+			t.Fatalf("expect %v on %s, got: %v", wantErr, field, err)
+		}
+	}
+	if model.Price != 1.5 || len(model.Items) != 2 || model.Items[0].Qty != 1 {
+		t.Fatalf("valid fields not copied: %+v", model)
+	}
+
+	// slice源对象的错误带上元素下标
+	var models []*strictModel
+	err = newDbCopier().Strict().Copy(&models, []map[string]any{{"id": 1}, {"age": 1.5, "count": -1}})
+	if !errors.As(err, &copyErr) || len(copyErr.Fields) != 2 {
+		t.Fatalf("expect 2 error fields, got: %v", err)
+	}
+	fields := []string{copyErr.Fields[0].Field, copyErr.Fields[1].Field}
+	slices.Sort(fields)
+	if !slices.Equal(fields, []string{"[1].age", "[1].count"}) {
+		t.Fatalf("unexpected error fields: %v", fields)
+	}
+}
+
+type dtoModel struct {
+	ID        int64       `boil:"id"`
+	Version   int64       `boil:"version"`
+	Nick      null.String `boil:"nick"`
+	Remark    null.String `boil:"remark"`
+	Extra     types.JSON  `boil:"extra"`
+	Tags      types.JSON  `boil:"tags"`
+	Attrs     null.JSON   `boil:"attrs"`
+	CreatedAt time.Time   `boil:"created_at"`
+	UpdatedAt null.Time   `boil:"updated_at"`
+	DeletedAt null.Time   `boil:"deleted_at"`
+}
+
+type dtoTag struct {
+	Name string `json:"name"`
+}
+
+type dtoResponse struct {
+	ID        int64
+	Version   int64
+	Nick      string
+	Remark    string
+	Extra     map[string]any
+	Tags      []dtoTag
+	Attrs     *structpb.Struct
+	CreatedAt int32
+	UpdatedAt string
+	DeletedAt int64
+}
+
+func TestCopyToDTO(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	model := &dtoModel{
+		ID:        1,
+		Version:   2,
+		Nick:      null.StringFrom("n"),
+		Extra:     types.JSON(`{"a":1}`),
+		Tags:      types.JSON(`[{"name":"x"}]`),
+		Attrs:     null.JSONFrom([]byte(`{"k":"v"}`)),
+		CreatedAt: now,
+		UpdatedAt: null.TimeFrom(now),
+	}
+
+	// 自增字段不再自增, Json反序列化, 无效的null.*转换为零值, 时间转换为Unix秒或字符串
+	var resp dtoResponse
+	if err := newDbCopier().TimeLocation(time.UTC).CopyToDTO(&resp, model); err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != 1 || resp.Version != 2 || resp.Nick != "n" || resp.Remark != "" || resp.DeletedAt != 0 {
+		t.Fatalf("unexpected copy result: %+v", resp)
+	}
+	if resp.Extra["a"] != float64(1) || len(resp.Tags) != 1 || resp.Tags[0].Name != "x" || resp.Attrs.GetFields()["k"].GetStringValue() != "v" {
+		t.Fatalf("json not unmarshalled: %+v", resp)
+	}
+	if resp.CreatedAt != int32(now.Unix()) || resp.UpdatedAt != "2024-01-02 03:04:05" {
+		t.Fatalf("unexpected time: %+v", resp)
+	}
+
+	// slice中的每个元素都反向拷贝
+	var list []*dtoResponse
+	if err := newDbCopier().CopyToDTO(&list, []*dtoModel{model, {ID: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Extra["a"] != float64(1) || list[1].ID != 2 || list[1].Extra != nil || list[1].CreatedAt != 0 {
+		t.Fatalf("unexpected copy result: %+v", list)
+	}
+}
+
+func TestCopyTimeToInteger(t *testing.T) {
+	type unixModel struct {
+		CreatedAt time.Time `boil:"created_at"`
+	}
+
+	after2038 := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	before1970 := time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 所有整数类型都可以保存Unix秒, 无法容纳时返回溢出错误
+	testCases := []struct {
+		name    string
+		dest    any
+		t       time.Time
+		wantErr error
+	}{
+		{name: "int", dest: &struct{ CreatedAt int }{}, t: after2038},
+		{name: "int32", dest: &struct{ CreatedAt int32 }{}, t: before1970},
+		{name: "int32 overflow", dest: &struct{ CreatedAt int32 }{}, t: after2038, wantErr: errOverflow},
+		{name: "int16 overflow", dest: &struct{ CreatedAt int16 }{}, t: before1970, wantErr: errOverflow},
+		{name: "uint32", dest: &struct{ CreatedAt uint32 }{}, t: after2038},
+		{name: "uint64", dest: &struct{ CreatedAt uint64 }{}, t: after2038},
+		{name: "uint before 1970", dest: &struct{ CreatedAt uint64 }{}, t: before1970, wantErr: errOverflow},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newDbCopier().CopyToDTO(tc.dest, &unixModel{CreatedAt: tc.t})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expect %v, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(reflect.ValueOf(tc.dest).Elem().Field(0).Interface()); got != fmt.Sprint(tc.t.Unix()) {
+				t.Fatalf("got %s, want %d", got, tc.t.Unix())
+			}
+		})
+	}
+}
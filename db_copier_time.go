@@ -0,0 +1,125 @@
+package sqlboiler
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+// Unix时间戳的数量级上限, 超过则认为是更高精度的时间戳
+const (
+	maxEpochSeconds = 1e11 // 约5138年
+	maxEpochMillis  = 1e14
+	maxEpochMicros  = 1e17
+)
+
+// handleTimeField 将源值转换为时间写入目标字段
+func (impl *dbCopierImpl) handleTimeField(field reflect.Value, value any) error {
+	t, err := impl.parseTime(value)
+	if err != nil {
+		return err
+	}
+	field.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// parseTime 支持time.Time, Unix时间戳和字符串, 时间戳自动识别秒/毫秒/微秒/纳秒,
+// 字符串依次尝试配置的格式, 没有时区的格式在配置的时区中解析, 空字符串和0为零值时间
+func (impl *dbCopierImpl) parseTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if v == "" {
+			return time.Time{}, nil
+		}
+
+		for _, layout := range impl.getTimeLayouts() {
+			if t, err := time.ParseInLocation(layout, v, impl.getLocation()); err == nil {
+				return t, nil
+			}
+		}
+
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return impl.fromEpoch(n), nil
+		}
+		return time.Time{}, fmt.Errorf("invalid time format: %s", v)
+	default:
+		if n, ok := impl.tryParseInt64(value); ok {
+			return impl.fromEpoch(n), nil
+		}
+		return time.Time{}, fmt.Errorf("unsupported time source: %T", value)
+	}
+}
+
+// setFieldFromTime 将时间拷贝到整数或字符串字段, 整数为Unix秒, 字符串使用第一个时间格式, 零值时间转换为零值
+// 整数字段无法容纳该时间的Unix秒时返回溢出错误, e.g: 2038年以后的时间拷贝到int32
+func (impl *dbCopierImpl) setFieldFromTime(field reflect.Value, t time.Time) error {
+	if t.IsZero() {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.OverflowInt(t.Unix()) {
+			return errOverflow
+		}
+		field.SetInt(t.Unix())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t.Unix() < 0 || field.OverflowUint(uint64(t.Unix())) {
+			return errOverflow
+		}
+		field.SetUint(uint64(t.Unix()))
+	case reflect.String:
+		field.SetString(t.In(impl.getLocation()).Format(impl.getTimeLayouts()[0]))
+	default:
+		return fmt.Errorf("unsupported time destination: %s", field.Type())
+	}
+	return nil
+}
+
+// fromEpoch 根据数量级识别Unix时间戳的精度
+func (impl *dbCopierImpl) fromEpoch(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+
+	var t time.Time
+	switch {
+	case abs < maxEpochSeconds:
+		t = time.Unix(n, 0)
+	case abs < maxEpochMillis:
+		t = time.UnixMilli(n)
+	case abs < maxEpochMicros:
+		t = time.UnixMicro(n)
+	default:
+		t = time.Unix(0, n)
+	}
+	return t.In(impl.getLocation())
+}
+
+func (impl *dbCopierImpl) getTimeLayouts() []string {
+	if len(impl.timeLayouts) > 0 {
+		return impl.timeLayouts
+	}
+	return defaultCopierProfile.TimeLayouts
+}
+
+func (impl *dbCopierImpl) getLocation() *time.Location {
+	if impl.location != nil {
+		return impl.location
+	}
+	if loc := boil.GetLocation(); loc != nil {
+		return loc
+	}
+	return time.UTC
+}
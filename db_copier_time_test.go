@@ -0,0 +1,184 @@
+package sqlboiler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aarondl/null/v8"
+	"github.com/aarondl/sqlboiler/v4/boil"
+)
+
+type timeModel struct {
+	CreatedAt time.Time  `boil:"created_at"`
+	UpdatedAt null.Time  `boil:"updated_at"`
+	DeletedAt *time.Time `boil:"deleted_at"`
+}
+
+// setBoilLocation 设置sqlboiler的时区, 测试结束后恢复
+func setBoilLocation(t *testing.T, loc *time.Location) {
+	t.Helper()
+	old := boil.GetLocation()
+	boil.SetLocation(loc)
+	t.Cleanup(func() {
+		boil.SetLocation(old)
+	})
+}
+
+func TestCopyTimeLayouts(t *testing.T) {
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		layouts []string
+		src     string
+		want    time.Time
+	}{
+		{name: "datetime", src: "2024-01-02 03:04:05", want: want},
+		{name: "rfc3339 with zone", src: "2024-01-02T11:04:05+08:00", want: want},
+		{name: "date only", src: "2024-01-02", want: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "without zone", src: "2024-01-02T03:04:05", want: want},
+		{name: "first layout wins", layouts: []string{"02/01/2006", "01/02/2006"}, src: "03/04/2024", want: time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{name: "fallback layout", layouts: []string{"02/01/2006", "01/02/2006"}, src: "12/31/2024", want: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			copier := newDbCopier().TimeLocation(time.UTC)
+			if len(tc.layouts) > 0 {
+				copier = copier.TimeLayouts(tc.layouts...)
+			}
+
+			var model timeModel
+			if err := copier.Copy(&model, map[string]any{"created_at": tc.src}); err != nil {
+				t.Fatal(err)
+			}
+			if !model.CreatedAt.Equal(tc.want) {
+				t.Fatalf("got %v, want %v", model.CreatedAt, tc.want)
+			}
+		})
+	}
+
+	var model timeModel
+	if err := newDbCopier().Copy(&model, map[string]any{"created_at": "2024/01/02"}); err == nil {
+		t.Fatal("expect invalid time format error")
+	}
+}
+
+func TestCopyTimeEpoch(t *testing.T) {
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	past := time.Date(1960, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// 根据数量级识别秒/毫秒/微秒/纳秒
+	testCases := []struct {
+		name string
+		src  any
+		want time.Time
+	}{
+		{name: "seconds", src: want.Unix(), want: want},
+		{name: "milliseconds", src: want.UnixMilli(), want: want},
+		{name: "microseconds", src: want.UnixMicro(), want: want},
+		{name: "nanoseconds", src: want.UnixNano(), want: want},
+		{name: "float seconds", src: float64(want.Unix()), want: want},
+		{name: "string seconds", src: "1704164645", want: want},
+		{name: "negative seconds", src: past.Unix(), want: past},
+		{name: "negative milliseconds", src: past.UnixMilli(), want: past},
+		{name: "zero", src: 0, want: time.Time{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var model timeModel
+			if err := newDbCopier().Copy(&model, map[string]any{"created_at": tc.src}); err != nil {
+				t.Fatal(err)
+			}
+			if !model.CreatedAt.Equal(tc.want) {
+				t.Fatalf("got %v, want %v", model.CreatedAt, tc.want)
+			}
+		})
+	}
+}
+
+func TestCopyTimeLocation(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	setBoilLocation(t, shanghai)
+
+	// 没有时区的字符串默认在boil.GetLocation()中解析, TimeLocation优先
+	var model timeModel
+	src := map[string]any{"created_at": "2024-01-02 03:04:05"}
+	if err := newDbCopier().Copy(&model, src); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, shanghai); !model.CreatedAt.Equal(want) {
+		t.Fatalf("got %v, want %v", model.CreatedAt, want)
+	}
+
+	if err := newDbCopier().TimeLocation(time.UTC).Copy(&model, src); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); !model.CreatedAt.Equal(want) {
+		t.Fatalf("got %v, want %v", model.CreatedAt, want)
+	}
+
+	// 时间戳转换到同样的时区, 反向拷贝时在该时区中格式化
+	if err := newDbCopier().Copy(&model, map[string]any{"created_at": time.Now().Unix()}); err != nil {
+		t.Fatal(err)
+	}
+	if model.CreatedAt.Location() != shanghai {
+		t.Fatalf("unexpected location: %v", model.CreatedAt.Location())
+	}
+
+	var resp struct {
+		CreatedAt string
+	}
+	model.CreatedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := newDbCopier().CopyToDTO(&resp, &model); err != nil {
+		t.Fatal(err)
+	}
+	if resp.CreatedAt != "2024-01-02 11:04:05" {
+		t.Fatalf("unexpected format: %s", resp.CreatedAt)
+	}
+	if err := newDbCopier().TimeLocation(time.UTC).CopyToDTO(&resp, &model); err != nil {
+		t.Fatal(err)
+	}
+	if resp.CreatedAt != "2024-01-02 03:04:05" {
+		t.Fatalf("unexpected format: %s", resp.CreatedAt)
+	}
+}
+
+func TestCopyTimeDestinations(t *testing.T) {
+	want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var model timeModel
+	if err := newDbCopier().TimeLocation(time.UTC).Copy(&model, map[string]any{"updated_at": "2024-01-02 03:04:05", "deleted_at": want.UnixMilli()}); err != nil {
+		t.Fatal(err)
+	}
+	if !model.UpdatedAt.Valid || !model.UpdatedAt.Time.Equal(want) || model.DeletedAt == nil || !model.DeletedAt.Equal(want) {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+
+	// 空字符串和nil将null.Time设置为无效值
+	if err := newDbCopier().Copy(&model, map[string]any{"updated_at": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if model.UpdatedAt.Valid {
+		t.Fatalf("empty string should be null: %+v", model.UpdatedAt)
+	}
+	model.UpdatedAt = null.TimeFrom(want)
+	if err := newDbCopier().Copy(&model, map[string]any{"updated_at": nil}); err != nil {
+		t.Fatal(err)
+	}
+	if model.UpdatedAt.Valid {
+		t.Fatalf("nil should be null: %+v", model.UpdatedAt)
+	}
+
+	// 源对象为null.Time和*time.Time
+	src := struct {
+		CreatedAt null.Time
+		UpdatedAt *time.Time
+	}{CreatedAt: null.TimeFrom(want), UpdatedAt: &want}
+	model = timeModel{}
+	if err := newDbCopier().Copy(&model, &src); err != nil {
+		t.Fatal(err)
+	}
+	if !model.CreatedAt.Equal(want) || !model.UpdatedAt.Valid || !model.UpdatedAt.Time.Equal(want) {
+		t.Fatalf("unexpected copy result: %+v", model)
+	}
+}
@@ -0,0 +1,325 @@
+package sqlboiler
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/aarondl/sqlboiler/v4/types"
+	"github.com/elliotchance/pie/v2"
+	"github.com/pkg/errors"
+)
+
+// FieldValidator 字段校验函数, value为拷贝后的目标字段值, 指针和null.*会被解引用, nil指针和无效的null为nil
+type FieldValidator func(value any) error
+
+// RuleFactory 根据validate tag中的规则参数创建校验函数, e.g: prefix=ORD 中的ORD
+type RuleFactory func(param string) (FieldValidator, error)
+
+// validateRule 编译后的validate tag规则
+type validateRule struct {
+	name     string
+	validate FieldValidator
+}
+
+const (
+	validateTagName = "validate"
+)
+
+var (
+	rulesCache    sync.Map // string => []validateRule
+	ruleFactories sync.Map // string => RuleFactory
+)
+
+// RegisterValidateRule 注册validate tag规则, 同名时覆盖内置规则, 应在程序启动时注册, e.g:
+//
+//	RegisterValidateRule("email", func(string) (FieldValidator, error) { return validateEmail, nil })
+func RegisterValidateRule(name string, factory RuleFactory) {
+	ruleFactories.Store(name, factory)
+	// 已编译的规则可能使用了旧的规则
+	rulesCache.Clear()
+}
+
+// ValidateMaxLen 字符串最大字符数, 用于限制和数据库varchar(n)一致的长度
+func ValidateMaxLen(n int) FieldValidator {
+	return func(value any) error {
+		if s, ok := value.(string); ok && utf8.RuneCountInString(s) > n {
+			return fmt.Errorf("must be at most %d characters", n)
+		}
+		return nil
+	}
+}
+
+// ValidateOneOf 值必须是values之一, 用于枚举, 按字符串形式比较
+func ValidateOneOf(values ...any) FieldValidator {
+	allowed := make([]string, len(values))
+	for i, v := range values {
+		allowed[i] = fmt.Sprint(v)
+	}
+	return func(value any) error {
+		if value != nil && !slices.Contains(allowed, fmt.Sprint(value)) {
+			return fmt.Errorf("must be one of [%s]", strings.Join(allowed, " "))
+		}
+		return nil
+	}
+}
+
+// ValidateNonNegative 数字不能为负数, 用于金额, 支持types.Decimal
+func ValidateNonNegative() FieldValidator {
+	return func(value any) error {
+		if n, ok := toFloat64(value); ok && n < 0 {
+			return errors.New("must not be negative")
+		}
+		return nil
+	}
+}
+
+// Validate 拷贝时按validate tag校验字段值, 优先使用目标字段的tag, 其次是源字段的, 只校验拷贝的字段,
+// CopyForCreate时源对象没有提供的字段也会按目标字段的required规则校验, e.g:
+//
+//	Name   string `validate:"required,max=64"`
+//	Status string `validate:"oneof=draft published"`
+//	Amount int64  `validate:"gte=0"`
+//
+// 支持的规则: required, omitempty, min, max, len(字符串为字符数, slice/map为长度, 数字为值), gt, gte, lt, lte, oneof,
+// 以及通过RegisterValidateRule注册的规则, 其他规则(例如交给其他校验库处理的email)忽略
+func (impl *dbCopierImpl) Validate() DbCopier {
+	impl.validate = true
+	return impl
+}
+
+// Validator 设置目标字段的校验函数, 不需要调用Validate也会执行
+func (impl *dbCopierImpl) Validator(field string, validators ...FieldValidator) DbCopier {
+	key := format(field)
+	impl.validators[key] = append(impl.validators[key], validators...)
+	return impl
+}
+
+// validateField 校验拷贝后的字段值, 校验失败的字段会被收集起来, 拷贝结束后一起返回, 只有规则本身错误时才返回错误
+func (impl *dbCopierImpl) validateField(destField reflect.Value, key, name, rules string, state *copyState) error {
+	validators := impl.validators[key]
+	if len(validators) == 0 && (!impl.validate || rules == "") {
+		return nil
+	}
+
+	value := getValidateValue(destField)
+	if impl.validate && rules != "" {
+		compiled, err := compileRules(rules)
+		if err != nil {
+			return errors.Wrapf(err, "validate field '%s'", name)
+		}
+
+		for _, rule := range compiled {
+			if rule.name == "omitempty" {
+				if value == nil || reflect.ValueOf(value).IsZero() {
+					break
+				}
+				continue
+			}
+
+			if err = rule.validate(value); err != nil {
+				state.addInvalid(name, rule.name, err)
+				return nil
+			}
+		}
+	}
+
+	for _, validate := range validators {
+		if err := validate(value); err != nil {
+			state.addInvalid(name, "", err)
+			return nil
+		}
+	}
+	return nil
+}
+
+// validateMissing CopyForCreate拷贝结束后按目标字段的required规则校验源对象中没有提供的字段, 忽略的字段不校验
+func (impl *dbCopierImpl) validateMissing(to reflect.Value, state *copyState) error {
+	if !impl.validate || state.action != copyActionCreate {
+		return nil
+	}
+
+	info := getTypeInfo(to.Type())
+	for _, key := range slices.Sorted(maps.Keys(info.rules)) {
+		name := info.key2name[key]
+		if impl.skip(key, state) || state.isInvalid(name) {
+			continue
+		}
+
+		compiled, err := compileRules(info.rules[key])
+		if err != nil {
+			return errors.Wrapf(err, "validate field '%s'", name)
+		}
+		if !slices.ContainsFunc(compiled, func(rule validateRule) bool { return rule.name == "required" }) {
+			continue
+		}
+
+		var value any
+		if destField := fieldByIndex(to, info.key2index[key]); destField.IsValid() {
+			value = getValidateValue(destField)
+		}
+		if err = validateRequired(value); err != nil {
+			state.addInvalid(name, "required", err)
+		}
+	}
+	return nil
+}
+
+// getValidateValue 获取用于校验的值, 解引用指针和null.*
+func getValidateValue(v reflect.Value) any {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if isNullType(v.Type()) {
+		value, valid := unwrapNull(v)
+		if !valid {
+			return nil
+		}
+		return value.Interface()
+	}
+	return v.Interface()
+}
+
+// compileRules 编译validate tag, 结果会被缓存
+func compileRules(rules string) ([]validateRule, error) {
+	if v, exist := rulesCache.Load(rules); exist {
+		return v.([]validateRule), nil
+	}
+
+	compiled := make([]validateRule, 0)
+	for _, item := range strings.Split(rules, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(item), "=")
+		if name == "" {
+			continue
+		}
+
+		validate, err := newRuleValidator(name, param)
+		if err != nil {
+			return nil, err
+		}
+		if validate == nil && name != "omitempty" {
+			continue // 不支持的规则
+		}
+		compiled = append(compiled, validateRule{name: name, validate: validate})
+	}
+
+	rulesCache.Store(rules, compiled)
+	return compiled, nil
+}
+
+// newRuleValidator 创建规则的校验函数, 优先使用注册的规则, 不支持的规则返回nil
+func newRuleValidator(name, param string) (FieldValidator, error) {
+	if factory, exist := ruleFactories.Load(name); exist {
+		validate, err := factory.(RuleFactory)(param)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid validate rule: %s=%s", name, param)
+		}
+		return validate, nil
+	}
+
+	switch name {
+	case "required":
+		return validateRequired, nil
+	case "omitempty":
+		return nil, nil
+	case "oneof":
+		values := pie.Map(strings.Fields(param), func(v string) any {
+			return v
+		})
+		return ValidateOneOf(values...), nil
+	case "min", "max", "len", "gt", "gte", "lt", "lte":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validate rule: %s=%s", name, param)
+		}
+		return newCompareValidator(name, n), nil
+	default:
+		return nil, nil
+	}
+}
+
+func validateRequired(value any) error {
+	if value == nil || reflect.ValueOf(value).IsZero() {
+		return errors.New("is required")
+	}
+	return nil
+}
+
+// newCompareValidator 比较规则, 字符串比较字符数, slice/map比较长度, 数字比较值, nil不校验
+func newCompareValidator(name string, n float64) FieldValidator {
+	return func(value any) error {
+		if value == nil {
+			return nil
+		}
+
+		actual, unit := float64(0), ""
+		switch v := reflect.ValueOf(value); v.Kind() {
+		case reflect.String:
+			actual, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+		case reflect.Slice, reflect.Map, reflect.Array:
+			actual, unit = float64(v.Len()), " items"
+		default:
+			f, ok := toFloat64(value)
+			if !ok {
+				return fmt.Errorf("cannot apply %s to %T", name, value)
+			}
+			actual = f
+		}
+
+		var ok bool
+		var message string
+		switch name {
+		case "min", "gte":
+			ok, message = actual >= n, "must be at least %v%s"
+		case "max", "lte":
+			ok, message = actual <= n, "must be at most %v%s"
+		case "len":
+			ok, message = actual == n, "must be exactly %v%s"
+		case "gt":
+			ok, message = actual > n, "must be greater than %v%s"
+		case "lt":
+			ok, message = actual < n, "must be less than %v%s"
+		}
+		if !ok {
+			return fmt.Errorf(message, n, unit)
+		}
+		return nil
+	}
+}
+
+// toFloat64 将数字或者types.Decimal转换为float64用于比较
+func toFloat64(value any) (float64, bool) {
+	switch v := value.(type) {
+	case types.Decimal:
+		if v.Big == nil {
+			return 0, true
+		}
+		return v.Float64()
+	case types.NullDecimal:
+		if v.Big == nil {
+			return 0, false
+		}
+		return v.Float64()
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,105 @@
+package sqlboiler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type ruleModel struct {
+	Email string `boil:"email" validate:"required,email"`
+	Sn    string `boil:"sn" validate:"omitempty,prefix=ORD"`
+}
+
+func TestValidateRules(t *testing.T) {
+	// 不支持的规则忽略, 其他规则仍然生效
+	var model ruleModel
+	if err := newDbCopier().Validate().Copy(&model, map[string]any{"email": "a@b.c", "sn": "X1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var validationErr *ValidationError
+	err := newDbCopier().Validate().Copy(&model, map[string]any{"email": ""})
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 1 || validationErr.Fields[0].Rule != "required" {
+		t.Fatalf("expect required error, got: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ruleFactories.Delete("prefix")
+		rulesCache.Clear()
+	})
+	RegisterValidateRule("prefix", func(param string) (FieldValidator, error) {
+		if param == "" {
+			return nil, errors.New("missing prefix")
+		}
+		return func(value any) error {
+			if s, ok := value.(string); ok && !strings.HasPrefix(s, param) {
+				return fmt.Errorf("must start with %s", param)
+			}
+			return nil
+		}, nil
+	})
+
+	// 注册后的规则对已经编译过的tag同样生效
+	err = newDbCopier().Validate().Copy(&model, map[string]any{"email": "a@b.c", "sn": "X1"})
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 1 || validationErr.Fields[0].Field != "sn" {
+		t.Fatalf("expect prefix error, got: %v", err)
+	}
+	if err = newDbCopier().Validate().Copy(&model, map[string]any{"email": "a@b.c", "sn": "ORD1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	type badRuleModel struct {
+		Sn string `validate:"prefix"`
+	}
+	var bad badRuleModel
+	if err = newDbCopier().Validate().Copy(&bad, map[string]any{"sn": "ORD1"}); err == nil || errors.As(err, &validationErr) {
+		t.Fatalf("expect rule error, got: %v", err)
+	}
+}
+
+func TestValidateRequiredOnCreate(t *testing.T) {
+	type createModel struct {
+		ID    int64  `boil:"id"`
+		Tid   int64  `boil:"tid" validate:"required"`
+		Name  string `boil:"name" validate:"required,max=5"`
+		Title string `boil:"title" validate:"required"`
+		Note  string `boil:"note"`
+	}
+
+	// 源对象没有提供的required字段也要校验, 忽略的字段和已经校验失败的字段不重复校验
+	var model createModel
+	err := newDbCopier().Validate().CopyForCreate(&model, map[string]any{"name": "toolong", "note": "n"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expect validation error, got: %v", err)
+	}
+	if got := fmt.Sprint(validationErr.Fields); got != "[name: must be at most 5 characters title: is required]" {
+		t.Fatalf("unexpected invalid fields: %s", got)
+	}
+
+	// 计算字段提供的值满足required
+	model = createModel{}
+	err = newDbCopier().Validate().ProvideOnCreate("title", func() (any, error) { return "t", nil }).
+		CopyForCreate(&model, map[string]any{"name": "n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 编辑和普通拷贝只校验拷贝的字段
+	if err = newDbCopier().Validate().CopyForEdit(&createModel{}, map[string]any{"note": "n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = newDbCopier().Validate().Copy(&createModel{}, map[string]any{"note": "n"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// slice元素的字段路径带下标
+	var models []*createModel
+	err = newDbCopier().Validate().CopyForCreate(&models, []map[string]any{{"name": "a", "title": "t"}, {"name": "b"}})
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 1 || validationErr.Fields[0].Field != "[1].title" {
+		t.Fatalf("expect title error at index 1, got: %v", err)
+	}
+}
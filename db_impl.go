@@ -1,25 +1,51 @@
 package sqlboiler
 
 import (
+	"context"
+
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/hdget/common/biz"
 )
 
 type Db interface {
 	Copier() DbCopier
-	Executor() boil.Executor
+	Executor() boil.Executor                // 写操作使用, 存在事务时返回事务, 否则返回主库
+	Reader(c context.Context) boil.Executor // 读操作使用, 存在事务或者设置了ReadYourWrites时返回Executor(), 否则返回从库
 }
 
 type dbImpl struct {
-	ctx    biz.Context
-	copier DbCopier
+	ctx     biz.Context
+	copier  DbCopier
+	name    string   // 数据库名, 为空表示默认数据库
+	cluster *Cluster // 创建时获取的数据库集群, 数据库未注册时为错误集群
+}
+
+func newDbImpl(ctx biz.Context, database ...string) *dbImpl {
+	name := getDatabase(database...)
+	return &dbImpl{
+		ctx:     ctx,
+		copier:  newDbCopier(),
+		name:    name,
+		cluster: resolveCluster(name),
+	}
 }
 
 func (impl *dbImpl) Executor() boil.Executor {
-	if tx, ok := impl.ctx.Transactor().GetTx().(boil.Transactor); ok {
-		return tx
+	if session := currentTxSession(impl.ctx, impl.name); session != nil {
+		return session
+	}
+	return impl.cluster.Primary()
+}
+
+func (impl *dbImpl) Reader(c context.Context) boil.Executor {
+	if session := currentTxSession(impl.ctx, impl.name); session != nil {
+		return session
+	}
+
+	if isReadYourWrites(c) {
+		return impl.cluster.Primary()
 	}
-	return boil.GetDB()
+	return impl.cluster.Replica()
 }
 
 func (impl *dbImpl) Copier() DbCopier {
@@ -12,11 +12,9 @@ type gdbImpl struct {
 	*dbImpl
 }
 
-func NewGdb(ctx biz.Context) Gdb {
+// NewGdb 创建通用db, 可以指定数据库名, 默认使用默认数据库
+func NewGdb(ctx biz.Context, database ...string) Gdb {
 	return &gdbImpl{
-		dbImpl: &dbImpl{
-			ctx:    ctx,
-			copier: newDbCopier(),
-		},
+		dbImpl: newDbImpl(ctx, database...),
 	}
 }
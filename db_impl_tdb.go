@@ -1,28 +1,257 @@
 package sqlboiler
 
 import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/aarondl/sqlboiler/v4/queries"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
 	"github.com/hdget/common/biz"
+	loggerUtils "github.com/hdget/utils/logger"
+	"github.com/pkg/errors"
 )
 
 // Tdb Tenant db
 type Tdb interface {
 	Db
-	Tid() int64 // 获取租户ID接口, 本来可以从ctx中获取，但为了区分Gdb和Tdb，强制实现冗余接口
+	Tid() int64                                                          // 获取租户ID接口, 本来可以从ctx中获取，但为了区分Gdb和Tdb，强制实现冗余接口
+	Scope(mods ...qm.QueryMod) ([]qm.QueryMod, error)                    // 在QueryMods前注入租户条件, 用于SELECT/UPDATE/DELETE
+	ScopeTable(table string, mods ...qm.QueryMod) ([]qm.QueryMod, error) // 同Scope, 租户字段带表名限定, 用于JOIN查询
+	Stamp(models ...any) error                                           // 插入前给model设置租户ID
+	CrossTenant(reason string) Gdb                                       // 跨租户查询的逃生通道, 必须说明原因, 会记录审计日志
 }
 
 type tdbImpl struct {
 	*dbImpl
 }
 
-func NewTdb(ctx biz.Context) Tdb {
+const (
+	tenantColumn = "tid" // 租户字段名
+)
+
+// whereClause 从sqlboiler未导出的where条件中读取的字段
+type whereClause struct {
+	kind        int64
+	clause      string
+	orSeparator bool
+}
+
+// whereKinds sqlboiler未导出的whereKind的值
+type whereKinds struct {
+	normal     int64
+	leftParen  int64
+	rightParen int64
+}
+
+var (
+	errInvalidTenant = errors.New("invalid tenant id")
+	errUngroupedOr   = errors.New("OR condition must be grouped with qm.Expr in tenant scoped query")
+	errInspectWhere  = errors.New("cannot inspect sqlboiler where clauses, tenant scope refused")
+	orKeyword        = regexp.MustCompile(`(?i)\bor\b`)
+
+	// getWhereKinds 通过qm.Expr生成的括号条件获取whereKind的值, 不依赖sqlboiler的内部常量
+	getWhereKinds = sync.OnceValues(func() (whereKinds, error) {
+		q := &queries.Query{}
+		qm.Expr(qm.Where("1=1")).Apply(q)
+		wheres, err := readWhereClauses(q)
+		if err != nil {
+			return whereKinds{}, err
+		}
+		if len(wheres) != 3 {
+			return whereKinds{}, errInspectWhere
+		}
+		return whereKinds{normal: wheres[1].kind, leftParen: wheres[0].kind, rightParen: wheres[2].kind}, nil
+	})
+)
+
+// NewTdb 创建租户db, 可以指定数据库名, 默认使用默认数据库
+func NewTdb(ctx biz.Context, database ...string) Tdb {
 	return &tdbImpl{
-		dbImpl: &dbImpl{
-			ctx:    ctx,
-			copier: newDbCopier(),
-		},
+		dbImpl: newDbImpl(ctx, database...),
 	}
 }
 
 func (impl *tdbImpl) Tid() int64 {
 	return impl.ctx.Tid()
 }
+
+// Scope 返回注入了租户条件的QueryMods, 租户条件总是位于第一个, 租户ID无效时返回错误, e.g:
+//
+//	mods, err := tdb.Scope(qm.Where("status=?", 1))
+//	models.Users(mods...).All(ctx, tdb.Executor())
+//
+// 租户条件与其他条件之间是AND关系, 顶层的OR条件会导致`tid=? AND a OR b`跨租户泄露,
+// 所以mods中存在顶层OR条件时返回错误, 需要使用qm.Expr将OR条件包裹起来, e.g:
+//
+//	tdb.Scope(qm.Expr(qm.Where("a=?", 1), qm.Or("b=?", 2)))
+func (impl *tdbImpl) Scope(mods ...qm.QueryMod) ([]qm.QueryMod, error) {
+	return impl.scope(tenantColumn, mods)
+}
+
+// ScopeTable 和Scope一样, 但租户字段使用表名限定, 避免JOIN查询时tid字段有歧义, e.g:
+//
+//	mods, err := tdb.ScopeTable(models.TableNames.Users, qm.InnerJoin("orders o ON o.user_id=users.id"))
+func (impl *tdbImpl) ScopeTable(table string, mods ...qm.QueryMod) ([]qm.QueryMod, error) {
+	return impl.scope(table+"."+tenantColumn, mods)
+}
+
+func (impl *tdbImpl) scope(column string, mods []qm.QueryMod) ([]qm.QueryMod, error) {
+	tid := impl.Tid()
+	if tid <= 0 {
+		return nil, errInvalidTenant
+	}
+
+	hasOr, err := hasTopLevelOr(mods)
+	if err != nil {
+		return nil, err
+	}
+	if hasOr {
+		return nil, errUngroupedOr
+	}
+
+	return slices.Concat([]qm.QueryMod{qm.Where(fmt.Sprintf("%s=?", column), tid)}, mods), nil
+}
+
+// Stamp 给model或者model slice设置租户ID, 用于INSERT前调用, e.g:
+//
+//	if err := tdb.Stamp(user); err != nil {...}
+//	err = user.Insert(ctx, tdb.Executor(), boil.Infer())
+func (impl *tdbImpl) Stamp(models ...any) error {
+	tid := impl.Tid()
+	if tid <= 0 {
+		return errInvalidTenant
+	}
+
+	for _, model := range models {
+		if err := stampTenant(reflect.ValueOf(model), tid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copier 返回的copier在CopyForCreate时自动设置租户ID, 租户ID无效时返回错误
+func (impl *tdbImpl) Copier() DbCopier {
+	return impl.dbImpl.Copier().ProvideOnCreate(tenantColumn, func() (any, error) {
+		tid := impl.Tid()
+		if tid <= 0 {
+			return nil, errInvalidTenant
+		}
+		return tid, nil
+	})
+}
+
+// CrossTenant 跨租户访问, 返回的Gdb不会注入租户条件, 每次调用都会记录审计日志
+func (impl *tdbImpl) CrossTenant(reason string) Gdb {
+	loggerUtils.Warn("cross tenant db access", "tid", impl.Tid(), "reason", reason)
+	return &gdbImpl{
+		dbImpl: newDbImpl(impl.ctx, impl.name),
+	}
+}
+
+func stampTenant(v reflect.Value, tid int64) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return errors.New("model is nil")
+		}
+		return stampTenant(v.Elem(), tid)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := stampTenant(v.Index(i), tid); err != nil {
+				return errors.Wrapf(err, "stamp model at index %d", i)
+			}
+		}
+		return nil
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if format(t.Field(i).Name) != tenantColumn {
+				continue
+			}
+
+			field := v.Field(i)
+			if !field.CanSet() {
+				return fmt.Errorf("tenant field of %s is not settable", t.Name())
+			}
+
+			switch field.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				field.SetInt(tid)
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				field.SetUint(uint64(tid))
+			default:
+				return fmt.Errorf("unsupported tenant field type: %s", field.Kind())
+			}
+			return nil
+		}
+		return fmt.Errorf("model %s has no tenant field", t.Name())
+	default:
+		return fmt.Errorf("unsupported model type: %s", v.Kind())
+	}
+}
+
+// hasTopLevelOr 检查QueryMods生成的where条件中是否有不在括号内的OR
+// sqlboiler没有导出where条件, 这里将mods应用到临时的Query上再通过反射读取, 无法读取时返回错误, 拒绝生成租户查询
+// 注意: 使用qm.Expr后sqlboiler不再自动给每个条件加括号, 此时条件语句中的OR也会暴露在顶层
+func hasTopLevelOr(mods []qm.QueryMod) (bool, error) {
+	kinds, err := getWhereKinds()
+	if err != nil {
+		return false, err
+	}
+
+	q := &queries.Query{}
+	for _, mod := range mods {
+		mod.Apply(q)
+	}
+	wheres, err := readWhereClauses(q)
+	if err != nil {
+		return false, err
+	}
+
+	manualParens := slices.ContainsFunc(wheres, func(w whereClause) bool {
+		return w.kind == kinds.leftParen || w.kind == kinds.rightParen
+	})
+
+	depth := 0
+	for _, w := range wheres {
+		if depth == 0 {
+			if w.orSeparator {
+				return true, nil
+			}
+			if manualParens && w.kind == kinds.normal && orKeyword.MatchString(w.clause) {
+				return true, nil
+			}
+		}
+
+		switch w.kind {
+		case kinds.leftParen:
+			depth++
+		case kinds.rightParen:
+			depth--
+		}
+	}
+	return false, nil
+}
+
+// readWhereClauses 通过反射读取Query中未导出的where条件, 字段不存在或者类型不符时返回错误
+func readWhereClauses(q *queries.Query) ([]whereClause, error) {
+	wheres := reflect.ValueOf(q).Elem().FieldByName("where")
+	if !wheres.IsValid() || wheres.Kind() != reflect.Slice || wheres.Type().Elem().Kind() != reflect.Struct {
+		return nil, errInspectWhere
+	}
+
+	result := make([]whereClause, wheres.Len())
+	for i := range result {
+		w := wheres.Index(i)
+		kind, clause, orSeparator := w.FieldByName("kind"), w.FieldByName("clause"), w.FieldByName("orSeparator")
+		if !kind.IsValid() || !kind.CanInt() || clause.Kind() != reflect.String || orSeparator.Kind() != reflect.Bool {
+			return nil, errInspectWhere
+		}
+		result[i] = whereClause{kind: kind.Int(), clause: clause.String(), orSeparator: orSeparator.Bool()}
+	}
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package sqlboiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aarondl/sqlboiler/v4/drivers"
+	"github.com/aarondl/sqlboiler/v4/queries"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	"github.com/pkg/errors"
+)
+
+type tenantModel struct {
+	ID   int64
+	Tid  int64
+	Name string
+}
+
+// buildScopedQuery 生成注入了租户条件的查询语句
+func buildScopedQuery(mods ...qm.QueryMod) (string, []any) {
+	q := &queries.Query{}
+	queries.SetDialect(q, &drivers.Dialect{LQ: '"', RQ: '"', UseIndexPlaceholders: true})
+	qm.Apply(q, append([]qm.QueryMod{qm.From("users")}, mods...)...)
+	return queries.BuildQuery(q)
+}
+
+func TestTdbScope(t *testing.T) {
+	tdb := NewTdb(newTestContext(5))
+
+	testCases := []struct {
+		name    string
+		mods    []qm.QueryMod
+		wantSql string
+		wantErr error
+	}{
+		{name: "no mods", wantSql: `WHERE (tid=$1);`},
+		{name: "and", mods: []qm.QueryMod{qm.Where("a=?", 1), qm.Where("b=?", 2)}, wantSql: `WHERE (tid=$1) AND (a=$2) AND (b=$3);`},
+		{name: "or in clause", mods: []qm.QueryMod{qm.Where("a=? OR b=?", 1, 2)}, wantSql: `WHERE (tid=$1) AND (a=$2 OR b=$3);`},
+		{name: "grouped or", mods: []qm.QueryMod{qm.Expr(qm.Where("a=?", 1), qm.Or("b=?", 2))}, wantSql: `WHERE tid=$1 AND (a=$2 OR b=$3);`},
+		{name: "top level or", mods: []qm.QueryMod{qm.Where("a=?", 1), qm.Or("b=?", 2)}, wantErr: errUngroupedOr},
+		{name: "or2", mods: []qm.QueryMod{qm.Where("a=?", 1), qm.Or2(qm.Expr(qm.Where("b=?", 2)))}, wantErr: errUngroupedOr},
+		{name: "or clause next to expr", mods: []qm.QueryMod{qm.Expr(qm.Where("a=?", 1)), qm.Where("b=? or c=?", 2, 3)}, wantErr: errUngroupedOr},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mods, err := tdb.Scope(tc.mods...)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expect %v, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sql, args := buildScopedQuery(mods...)
+			if !strings.HasSuffix(sql, tc.wantSql) || args[0] != int64(5) {
+				t.Fatalf("unexpected query: %s %v", sql, args)
+			}
+		})
+	}
+}
+
+func TestTdbScopeTable(t *testing.T) {
+	mods, err := NewTdb(newTestContext(5)).ScopeTable("users", qm.InnerJoin("orders o ON o.user_id=users.id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql, _ := buildScopedQuery(mods...); !strings.Contains(sql, "WHERE (users.tid=$1)") {
+		t.Fatalf("tenant column not qualified: %s", sql)
+	}
+}
+
+func TestTdbInvalidTenant(t *testing.T) {
+	for _, tid := range []int64{0, -1} {
+		tdb := NewTdb(newTestContext(tid))
+		if _, err := tdb.Scope(); !errors.Is(err, errInvalidTenant) {
+			t.Fatalf("Scope with tid %d: %v", tid, err)
+		}
+		if _, err := tdb.ScopeTable("users"); !errors.Is(err, errInvalidTenant) {
+			t.Fatalf("ScopeTable with tid %d: %v", tid, err)
+		}
+		if err := tdb.Stamp(&tenantModel{}); !errors.Is(err, errInvalidTenant) {
+			t.Fatalf("Stamp with tid %d: %v", tid, err)
+		}
+		if err := tdb.Copier().CopyForCreate(&tenantModel{}, map[string]any{"name": "n"}); !errors.Is(err, errInvalidTenant) {
+			t.Fatalf("Copier with tid %d: %v", tid, err)
+		}
+	}
+}
+
+func TestTdbStamp(t *testing.T) {
+	tdb := NewTdb(newTestContext(5))
+
+	single := &tenantModel{}
+	slice := []*tenantModel{{ID: 1}, {ID: 2}}
+	values := []tenantModel{{ID: 3}}
+	if err := tdb.Stamp(single, slice, &values); err != nil {
+		t.Fatal(err)
+	}
+	if single.Tid != 5 || slice[0].Tid != 5 || slice[1].Tid != 5 || values[0].Tid != 5 {
+		t.Fatalf("tenant not stamped: %+v %+v %+v %+v", single, slice[0], slice[1], values)
+	}
+
+	type uintTenantModel struct {
+		Tid uint32
+	}
+	unsigned := &uintTenantModel{}
+	if err := tdb.Stamp(unsigned); err != nil || unsigned.Tid != 5 {
+		t.Fatalf("unsigned tenant not stamped: %v %+v", err, unsigned)
+	}
+
+	type noTenantModel struct {
+		ID int64
+	}
+	testCases := []struct {
+		name  string
+		model any
+	}{
+		{name: "nil pointer", model: (*tenantModel)(nil)},
+		{name: "nil element", model: []*tenantModel{{}, nil}},
+		{name: "value", model: tenantModel{}},
+		{name: "no tenant field", model: &noTenantModel{}},
+		{name: "not struct", model: new(int)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tdb.Stamp(tc.model); err == nil {
+				t.Fatal("expect error")
+			}
+		})
+	}
+}
+
+func TestWhereKinds(t *testing.T) {
+	kinds, err := getWhereKinds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kinds.normal == kinds.leftParen || kinds.normal == kinds.rightParen || kinds.leftParen == kinds.rightParen {
+		t.Fatalf("unexpected where kinds: %+v", kinds)
+	}
+}
@@ -3,21 +3,23 @@ module github.com/hdget/lib-sqlboiler
 go 1.23.1
 
 require (
+	github.com/aarondl/null/v8 v8.1.3
 	github.com/aarondl/sqlboiler/v4 v4.19.5
 	github.com/elliotchance/pie/v2 v2.9.1
+	github.com/ericlagergren/decimal v0.0.0-20190420051523-6335edbaa640
 	github.com/hdget/common v0.1.9
 	github.com/hdget/utils v0.0.4
 	github.com/iancoleman/strcase v0.3.0
+	github.com/mattn/go-sqlite3 v1.14.32
 	github.com/pkg/errors v0.9.1
+	google.golang.org/protobuf v1.36.6
 )
 
 require (
 	github.com/aarondl/inflect v0.0.2 // indirect
-	github.com/aarondl/null/v8 v8.1.3 // indirect
 	github.com/aarondl/randomize v0.0.2 // indirect
 	github.com/aarondl/strmangle v0.0.9 // indirect
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
-	github.com/ericlagergren/decimal v0.0.0-20190420051523-6335edbaa640 // indirect
 	github.com/friendsofgo/errors v0.9.2 // indirect
 	github.com/gofrs/uuid v4.2.0+incompatible // indirect
 	github.com/lib/pq v1.10.6 // indirect
@@ -33,5 +35,4 @@ require (
 	golang.org/x/xerrors v0.0.0-20220907171357-04be3eba64a2 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
 	google.golang.org/grpc v1.75.1 // indirect
-	google.golang.org/protobuf v1.36.6 // indirect
 )
@@ -2,7 +2,9 @@ package sqlboiler
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,8 +19,10 @@ import (
 
 type SQLHelper interface {
 	IfNull(column string, defaultValue any, args ...string) string
-	JsonValue(jsonColumn string, jsonKey string, defaultValue any) qm.QueryMod
-	JsonValueCompare(jsonColumn string, jsonKey string, operator string, compareValue any) qm.QueryMod
+	JsonValue(jsonColumn string, jsonKey string, defaultValue any) qm.QueryMod                                   // 不支持的类型返回nil, 建议使用JsonValueE
+	JsonValueE(jsonColumn string, jsonKey string, defaultValue any) (qm.QueryMod, error)                         // 获取Json字段中的值, 默认值会转义
+	JsonValueCompare(jsonColumn string, jsonKey string, operator string, compareValue any) qm.QueryMod           // 不支持的类型或操作符返回nil, 建议使用JsonValueCompareE
+	JsonValueCompareE(jsonColumn string, jsonKey string, operator string, compareValue any) (qm.QueryMod, error) // 比较Json字段中的值, 比较值以参数形式绑定
 	SUM(col string, args ...string) string
 	InnerJoin(joinTable string, args ...string) *JoinClauseBuilder
 	LeftJoin(joinTable string, args ...string) *JoinClauseBuilder
@@ -108,6 +112,87 @@ func (b baseHelper) OrderBy() *OrderByHelper {
 	return &OrderByHelper{tokens: make([]string, 0), quote: b.identifierQuote}
 }
 
+type jsonValueKind int
+
+const (
+	jsonValueKindUnknown jsonValueKind = iota
+	jsonValueKindString
+	jsonValueKindNumber
+)
+
+var (
+	// jsonCompareOperators Json比较允许使用的操作符
+	jsonCompareOperators = map[string]struct{}{
+		"=":        {},
+		"!=":       {},
+		"<>":       {},
+		">":        {},
+		">=":       {},
+		"<":        {},
+		"<=":       {},
+		"LIKE":     {},
+		"NOT LIKE": {},
+	}
+
+	// likeOperators 只能用于字符串的操作符
+	likeOperators = map[string]struct{}{
+		"LIKE":     {},
+		"NOT LIKE": {},
+	}
+)
+
+// checkOperator 检查比较操作符是否在白名单中, 返回规范化后的操作符
+func checkOperator(operator string) (string, error) {
+	op := strings.ToUpper(strings.Join(strings.Fields(operator), " "))
+	if _, exist := jsonCompareOperators[op]; !exist {
+		return "", fmt.Errorf("unsupported operator: %s", operator)
+	}
+	return op, nil
+}
+
+// splitJsonKey 将json key按.分割成路径, e.g: a.b => [a, b]
+func splitJsonKey(jsonKey string) ([]string, error) {
+	parts := strings.Split(jsonKey, ".")
+	for _, p := range parts {
+		if p == "" {
+			return nil, fmt.Errorf("invalid json key: %s", jsonKey)
+		}
+	}
+	return parts, nil
+}
+
+// parseJsonValue 解析Json值的类型, 返回其SQL字面量(字符串未转义)和用于绑定的参数
+func parseJsonValue(value any) (jsonValueKind, string, any, error) {
+	switch vv := reflect.ValueOf(value); vv.Kind() {
+	case reflect.String:
+		return jsonValueKindString, vv.String(), vv.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return jsonValueKindNumber, strconv.FormatInt(vv.Int(), 10), vv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonValueKindNumber, strconv.FormatUint(vv.Uint(), 10), vv.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		f := vv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return jsonValueKindUnknown, "", nil, fmt.Errorf("invalid number: %v", value)
+		}
+		return jsonValueKindNumber, strconv.FormatFloat(f, 'f', -1, 64), f, nil
+	}
+	return jsonValueKindUnknown, "", nil, fmt.Errorf("unsupported value type: %T", value)
+}
+
+// quoteLiteral 将字符串转义成SQL字符串字面量, mysql默认将反斜杠视为转义符, 需要额外转义
+func quoteLiteral(s string, escapeBackslash bool) string {
+	if escapeBackslash {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// escapeJsonPathKey 转义Json路径中用双引号包裹的key
+func escapeJsonPathKey(key string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(key, `\`, `\\`), `"`, `\"`) + `"`
+}
+
 // GetLimitQueryMods 获取Limit相关QueryMods
 func GetLimitQueryMods(list *protobuf.ListParam) []qm.QueryMod {
 	p := getPaginator(list)
@@ -2,6 +2,8 @@ package sqlboiler
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
 )
 
@@ -22,32 +24,76 @@ func Mysql() SQLHelper {
 	}
 }
 
-func (mysqlHelper) JsonValue(jsonColumn string, jsonKey string, defaultValue any) qm.QueryMod {
+func (h mysqlHelper) JsonValue(jsonColumn string, jsonKey string, defaultValue any) qm.QueryMod {
+	mod, _ := h.JsonValueE(jsonColumn, jsonKey, defaultValue)
+	return mod
+}
+
+func (h mysqlHelper) JsonValueE(jsonColumn string, jsonKey string, defaultValue any) (qm.QueryMod, error) {
+	path, err := mysqlJsonPath(jsonKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, literal, _, err := parseJsonValue(defaultValue)
+	if err != nil {
+		return nil, err
+	}
+
+	// select中无法绑定参数, 所有值都必须转义
 	var template string
-	switch v := defaultValue.(type) {
-	case string:
-		template = fmt.Sprintf("IFNULL(JSON_UNQUOTE(JSON_EXTRACT(%s, '$.%s')), '%s') AS %s", jsonColumn, jsonKey, v, jsonKey)
-	case int8, int, int32, int64:
-		template = fmt.Sprintf("IFNULL(JSON_EXTRACT(%s, '$.%s'), %d) AS %s", jsonColumn, jsonKey, v, jsonKey)
-	case float32, float64:
-		template = fmt.Sprintf("IFNULL(JSON_EXTRACT(%s, '$.%s'), %f) AS %s", jsonColumn, jsonKey, v, jsonKey)
+	switch kind {
+	case jsonValueKindString:
+		template = fmt.Sprintf("IFNULL(JSON_UNQUOTE(JSON_EXTRACT(%s, %s)), %s) AS %s", h.Quote(jsonColumn, true), quoteLiteral(path, true), quoteLiteral(literal, true), h.Quote(jsonKey))
 	default:
-		return nil
+		template = fmt.Sprintf("IFNULL(JSON_EXTRACT(%s, %s), %s) AS %s", h.Quote(jsonColumn, true), quoteLiteral(path, true), literal, h.Quote(jsonKey))
 	}
-	return qm.Select(template)
+	return qm.Select(template), nil
 }
 
-func (mysqlHelper) JsonValueCompare(jsonColumn string, jsonKey string, operator string, compareValue any) qm.QueryMod {
+func (h mysqlHelper) JsonValueCompare(jsonColumn string, jsonKey string, operator string, compareValue any) qm.QueryMod {
+	mod, _ := h.JsonValueCompareE(jsonColumn, jsonKey, operator, compareValue)
+	return mod
+}
+
+func (h mysqlHelper) JsonValueCompareE(jsonColumn string, jsonKey string, operator string, compareValue any) (qm.QueryMod, error) {
+	op, err := checkOperator(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := mysqlJsonPath(jsonKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, _, arg, err := parseJsonValue(compareValue)
+	if err != nil {
+		return nil, err
+	}
+
 	var template string
-	switch v := compareValue.(type) {
-	case string:
-		template = fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '$.%s')) %s '%s'", jsonColumn, jsonKey, operator, v)
-	case int8, int, int32, int64:
-		template = fmt.Sprintf("JSON_EXTRACT(%s, '$.%s') %s %d", jsonColumn, jsonKey, operator, v)
-	case float32, float64:
-		template = fmt.Sprintf("JSON_EXTRACT(%s, '$.%s') %s %f", jsonColumn, jsonKey, operator, v)
+	switch kind {
+	case jsonValueKindString:
+		template = fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, ?)) %s ?", h.Quote(jsonColumn, true), op)
 	default:
-		return nil
+		template = fmt.Sprintf("JSON_EXTRACT(%s, ?) %s ?", h.Quote(jsonColumn, true), op)
+	}
+	return qm.Where(template, path, arg), nil
+}
+
+// mysqlJsonPath 生成mysql的json路径, e.g: a.b => $."a"."b"
+func mysqlJsonPath(jsonKey string) (string, error) {
+	keys, err := splitJsonKey(jsonKey)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	builder.WriteString("$")
+	for _, key := range keys {
+		builder.WriteString(".")
+		builder.WriteString(escapeJsonPathKey(key))
 	}
-	return qm.Where(template)
+	return builder.String(), nil
 }
@@ -0,0 +1,102 @@
+package sqlboiler
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/aarondl/sqlboiler/v4/drivers"
+	"github.com/aarondl/sqlboiler/v4/queries"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+var mysqlDialect = drivers.Dialect{LQ: '`', RQ: '`'}
+
+// buildDialectQuery 生成QueryMods对应的SQL语句和参数
+func buildDialectQuery(dialect *drivers.Dialect, mods ...qm.QueryMod) (string, []any) {
+	q := &queries.Query{}
+	queries.SetDialect(q, dialect)
+	qm.Apply(q, mods...)
+	return queries.BuildQuery(q)
+}
+
+func TestMysqlJsonValue(t *testing.T) {
+	h := Mysql()
+
+	testCases := []struct {
+		name         string
+		jsonKey      string
+		defaultValue any
+		wantSql      string
+	}{
+		{name: "string", jsonKey: "city", defaultValue: "unknown", wantSql: "SELECT IFNULL(JSON_UNQUOTE(JSON_EXTRACT(`users`.`profile`, '$.\"city\"')), 'unknown') AS `city` FROM `users`;"},
+		{name: "escaped default", jsonKey: "city", defaultValue: `it's a\b`, wantSql: "SELECT IFNULL(JSON_UNQUOTE(JSON_EXTRACT(`users`.`profile`, '$.\"city\"')), 'it''s a\\\\b') AS `city` FROM `users`;"},
+		{name: "number", jsonKey: "vip.level", defaultValue: uint8(2), wantSql: "SELECT IFNULL(JSON_EXTRACT(`users`.`profile`, '$.\"vip\".\"level\"'), 2) AS `vip.level` FROM `users`;"},
+		{name: "float", jsonKey: "score", defaultValue: 1.5, wantSql: "SELECT IFNULL(JSON_EXTRACT(`users`.`profile`, '$.\"score\"'), 1.5) AS `score` FROM `users`;"},
+		{name: "escaped key", jsonKey: `a"b\c`, defaultValue: 0, wantSql: "SELECT IFNULL(JSON_EXTRACT(`users`.`profile`, '$.\"a\\\\\"b\\\\\\\\c\"'), 0) AS `a\"b\\c` FROM `users`;"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mod, err := h.JsonValueE("users.profile", tc.jsonKey, tc.defaultValue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql, args := buildDialectQuery(&mysqlDialect, mod, qm.From(h.Quote("users"))); sql != tc.wantSql || len(args) != 0 {
+				t.Fatalf("got %s %v, want %s", sql, args, tc.wantSql)
+			}
+		})
+	}
+
+	for _, jsonKey := range []string{"", "a..b", "a."} {
+		if _, err := h.JsonValueE("users.profile", jsonKey, 0); err == nil {
+			t.Fatalf("invalid json key %q should return error", jsonKey)
+		}
+	}
+	for _, value := range []any{nil, []int{1}, math.NaN(), math.Inf(1)} {
+		if h.JsonValue("users.profile", "age", value) != nil {
+			t.Fatalf("unsupported default value %v should return nil", value)
+		}
+	}
+}
+
+func TestMysqlJsonValueCompare(t *testing.T) {
+	h := Mysql()
+
+	testCases := []struct {
+		name         string
+		jsonKey      string
+		operator     string
+		compareValue any
+		wantSql      string
+		wantArgs     []any
+	}{
+		{name: "string", jsonKey: "city", operator: "=", compareValue: "x' OR '1'='1", wantSql: "SELECT * FROM `users` WHERE (JSON_UNQUOTE(JSON_EXTRACT(`users`.`profile`, ?)) = ?);", wantArgs: []any{`$."city"`, "x' OR '1'='1"}},
+		{name: "like", jsonKey: "city", operator: " not  like ", compareValue: "r%", wantSql: "SELECT * FROM `users` WHERE (JSON_UNQUOTE(JSON_EXTRACT(`users`.`profile`, ?)) NOT LIKE ?);", wantArgs: []any{`$."city"`, "r%"}},
+		{name: "int", jsonKey: "age", operator: ">=", compareValue: int32(20), wantSql: "SELECT * FROM `users` WHERE (JSON_EXTRACT(`users`.`profile`, ?) >= ?);", wantArgs: []any{`$."age"`, int64(20)}},
+		{name: "uint", jsonKey: "age", operator: "<>", compareValue: uint(20), wantSql: "SELECT * FROM `users` WHERE (JSON_EXTRACT(`users`.`profile`, ?) <> ?);", wantArgs: []any{`$."age"`, uint64(20)}},
+		{name: "escaped key", jsonKey: `vip.a"b\c`, operator: "=", compareValue: "gold", wantSql: "SELECT * FROM `users` WHERE (JSON_UNQUOTE(JSON_EXTRACT(`users`.`profile`, ?)) = ?);", wantArgs: []any{`$."vip"."a\"b\\c"`, "gold"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mod, err := h.JsonValueCompareE("users.profile", tc.jsonKey, tc.operator, tc.compareValue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql, args := buildDialectQuery(&mysqlDialect, qm.From(h.Quote("users")), mod); sql != tc.wantSql || !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Fatalf("got %s %#v, want %s %#v", sql, args, tc.wantSql, tc.wantArgs)
+			}
+		})
+	}
+
+	for _, operator := range []string{"; DROP TABLE users", "IN", "LIKE '%'", "==", ""} {
+		if _, err := h.JsonValueCompareE("users.profile", "age", operator, 1); err == nil {
+			t.Fatalf("unsupported operator %q should return error", operator)
+		}
+	}
+	if h.JsonValueCompare("users.profile", "a..b", "=", 1) != nil {
+		t.Fatal("invalid json key should return nil")
+	}
+	if _, err := h.JsonValueCompareE("users.profile", "age", "=", true); err == nil {
+		t.Fatal("unsupported compare value should return error")
+	}
+}
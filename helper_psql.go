@@ -2,6 +2,8 @@ package sqlboiler
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
 )
 
@@ -22,36 +24,87 @@ func Psql() SQLHelper {
 	}
 }
 
-func (psqlHelper) JsonValue(jsonColumn string, jsonKey string, defaultValue any) qm.QueryMod {
+func (h psqlHelper) JsonValue(jsonColumn string, jsonKey string, defaultValue any) qm.QueryMod {
+	mod, _ := h.JsonValueE(jsonColumn, jsonKey, defaultValue)
+	return mod
+}
+
+func (h psqlHelper) JsonValueE(jsonColumn string, jsonKey string, defaultValue any) (qm.QueryMod, error) {
+	path, err := psqlJsonPath(jsonKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, literal, _, err := parseJsonValue(defaultValue)
+	if err != nil {
+		return nil, err
+	}
+
+	// select中无法绑定参数, 所有值都必须转义
 	var template string
-	switch v := defaultValue.(type) {
-	case string:
-		template = fmt.Sprintf("COALESCE(%s->>'%s', '%s') AS %s", jsonColumn, jsonKey, v, jsonKey)
-	case int8, int, int32, int64:
-		template = fmt.Sprintf("COALESCE((%s->>'%s')::numeric, %d) AS %s", jsonColumn, jsonKey, v, jsonKey)
-	case float32, float64:
-		template = fmt.Sprintf("COALESCE((%s->>'%s')::numeric, %d) AS %s", jsonColumn, jsonKey, v, jsonKey)
+	switch kind {
+	case jsonValueKindString:
+		template = fmt.Sprintf("COALESCE(%s #>> %s, %s) AS %s", h.Quote(jsonColumn, true), quoteLiteral(path, false), quoteLiteral(literal, false), h.Quote(jsonKey))
 	default:
-		return nil
+		template = fmt.Sprintf("COALESCE((%s #>> %s)::numeric, %s) AS %s", h.Quote(jsonColumn, true), quoteLiteral(path, false), literal, h.Quote(jsonKey))
 	}
-	return qm.Select(template)
+	return qm.Select(template), nil
 }
 
-func (psqlHelper) JsonValueCompare(jsonColumn string, jsonKey string, operator string, compareValue any) qm.QueryMod {
+func (h psqlHelper) JsonValueCompare(jsonColumn string, jsonKey string, operator string, compareValue any) qm.QueryMod {
+	mod, _ := h.JsonValueCompareE(jsonColumn, jsonKey, operator, compareValue)
+	return mod
+}
+
+func (h psqlHelper) JsonValueCompareE(jsonColumn string, jsonKey string, operator string, compareValue any) (qm.QueryMod, error) {
+	op, err := checkOperator(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := psqlJsonPath(jsonKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, _, arg, err := parseJsonValue(compareValue)
+	if err != nil {
+		return nil, err
+	}
+
 	var template string
-	switch v := compareValue.(type) {
-	case string:
-		template = fmt.Sprintf("(%s->>'%s') %s '%s'", jsonColumn, jsonKey, operator, v)
-	case int8, int, int32, int64:
-		template = fmt.Sprintf("(%s->>'%s') %s %d", jsonColumn, jsonKey, operator, v)
-	case float32, float64:
-		template = fmt.Sprintf("(%s->>'%s') %s %f", jsonColumn, jsonKey, operator, v)
+	switch kind {
+	case jsonValueKindString:
+		template = fmt.Sprintf("(%s #>> ?) %s ?", h.Quote(jsonColumn, true), op)
 	default:
-		return nil
+		// postgres中numeric类型不支持LIKE
+		if _, exist := likeOperators[op]; exist {
+			return nil, fmt.Errorf("operator %s only supports string value", op)
+		}
+		template = fmt.Sprintf("(%s #>> ?)::numeric %s ?", h.Quote(jsonColumn, true), op)
 	}
-	return qm.Where(template)
+	return qm.Where(template, path, arg), nil
 }
 
 func (h psqlHelper) SUM(col string, args ...string) string {
 	return h.IfNull(fmt.Sprintf("SUM(%s)", col), 0, args...)
 }
+
+// psqlJsonPath 生成postgres的json路径数组, e.g: a.b => {"a","b"}
+func psqlJsonPath(jsonKey string) (string, error) {
+	keys, err := splitJsonKey(jsonKey)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	builder.WriteString("{")
+	for i, key := range keys {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString(escapeJsonPathKey(key))
+	}
+	builder.WriteString("}")
+	return builder.String(), nil
+}
@@ -0,0 +1,85 @@
+package sqlboiler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aarondl/sqlboiler/v4/drivers"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+var psqlDialect = drivers.Dialect{LQ: '"', RQ: '"', UseIndexPlaceholders: true}
+
+func TestPsqlJsonValue(t *testing.T) {
+	h := Psql()
+
+	testCases := []struct {
+		name         string
+		jsonKey      string
+		defaultValue any
+		wantSql      string
+	}{
+		{name: "string", jsonKey: "city", defaultValue: "unknown", wantSql: `SELECT COALESCE("users"."profile" #>> '{"city"}', 'unknown') AS "city" FROM "users";`},
+		{name: "escaped default", jsonKey: "city", defaultValue: `it's a\b`, wantSql: `SELECT COALESCE("users"."profile" #>> '{"city"}', 'it''s a\b') AS "city" FROM "users";`},
+		{name: "number", jsonKey: "vip.level", defaultValue: int8(-2), wantSql: `SELECT COALESCE(("users"."profile" #>> '{"vip","level"}')::numeric, -2) AS "vip.level" FROM "users";`},
+		{name: "float", jsonKey: "score", defaultValue: float32(1.5), wantSql: `SELECT COALESCE(("users"."profile" #>> '{"score"}')::numeric, 1.5) AS "score" FROM "users";`},
+		{name: "escaped key", jsonKey: `a"b\c`, defaultValue: "", wantSql: `SELECT COALESCE("users"."profile" #>> '{"a\"b\\c"}', '') AS "a""b\c" FROM "users";`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mod, err := h.JsonValueE("users.profile", tc.jsonKey, tc.defaultValue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql, args := buildDialectQuery(&psqlDialect, mod, qm.From(h.Quote("users"))); sql != tc.wantSql || len(args) != 0 {
+				t.Fatalf("got %s %v, want %s", sql, args, tc.wantSql)
+			}
+		})
+	}
+
+	if _, err := h.JsonValueE("users.profile", "a..b", 0); err == nil {
+		t.Fatal("invalid json key should return error")
+	}
+	if h.JsonValue("users.profile", "age", map[string]any{}) != nil {
+		t.Fatal("unsupported default value should return nil")
+	}
+}
+
+func TestPsqlJsonValueCompare(t *testing.T) {
+	h := Psql()
+
+	testCases := []struct {
+		name         string
+		jsonKey      string
+		operator     string
+		compareValue any
+		wantSql      string
+		wantArgs     []any
+	}{
+		{name: "string", jsonKey: "city", operator: "=", compareValue: "x' OR '1'='1", wantSql: `SELECT * FROM "users" WHERE (("users"."profile" #>> $1) = $2);`, wantArgs: []any{`{"city"}`, "x' OR '1'='1"}},
+		{name: "like", jsonKey: "city", operator: "like", compareValue: "r%", wantSql: `SELECT * FROM "users" WHERE (("users"."profile" #>> $1) LIKE $2);`, wantArgs: []any{`{"city"}`, "r%"}},
+		{name: "number", jsonKey: "age", operator: "<", compareValue: 25.5, wantSql: `SELECT * FROM "users" WHERE (("users"."profile" #>> $1)::numeric < $2);`, wantArgs: []any{`{"age"}`, 25.5}},
+		{name: "escaped key", jsonKey: `vip.a"b\c`, operator: "!=", compareValue: uint16(1), wantSql: `SELECT * FROM "users" WHERE (("users"."profile" #>> $1)::numeric != $2);`, wantArgs: []any{`{"vip","a\"b\\c"}`, uint64(1)}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mod, err := h.JsonValueCompareE("users.profile", tc.jsonKey, tc.operator, tc.compareValue)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql, args := buildDialectQuery(&psqlDialect, qm.From(h.Quote("users")), mod); sql != tc.wantSql || !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Fatalf("got %s %#v, want %s %#v", sql, args, tc.wantSql, tc.wantArgs)
+			}
+		})
+	}
+
+	// numeric类型不支持LIKE
+	for _, operator := range []string{"LIKE", "not like"} {
+		if _, err := h.JsonValueCompareE("users.profile", "age", operator, 20); err == nil {
+			t.Fatalf("%s with number should return error", operator)
+		}
+	}
+	if h.JsonValueCompare("users.profile", "age", "~", "a") != nil {
+		t.Fatal("unsupported operator should return nil")
+	}
+}
@@ -0,0 +1,72 @@
+package sqlboiler
+
+import (
+	"fmt"
+
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+)
+
+type sqliteHelper struct {
+	*baseHelper
+}
+
+const (
+	sqliteIdentifierQuote = "\""
+)
+
+func Sqlite() SQLHelper {
+	return &sqliteHelper{
+		&baseHelper{
+			identifierQuote: sqliteIdentifierQuote,
+			functionIfNull:  "IFNULL",
+		},
+	}
+}
+
+func (h sqliteHelper) JsonValue(jsonColumn string, jsonKey string, defaultValue any) qm.QueryMod {
+	mod, _ := h.JsonValueE(jsonColumn, jsonKey, defaultValue)
+	return mod
+}
+
+func (h sqliteHelper) JsonValueE(jsonColumn string, jsonKey string, defaultValue any) (qm.QueryMod, error) {
+	path, err := mysqlJsonPath(jsonKey) // sqlite和mysql的json路径语法一致
+	if err != nil {
+		return nil, err
+	}
+
+	kind, literal, _, err := parseJsonValue(defaultValue)
+	if err != nil {
+		return nil, err
+	}
+
+	// select中无法绑定参数, 所有值都必须转义, sqlite的json_extract对字符串已经去掉了引号
+	if kind == jsonValueKindString {
+		literal = quoteLiteral(literal, false)
+	}
+	template := fmt.Sprintf("IFNULL(json_extract(%s, %s), %s) AS %s", h.Quote(jsonColumn, true), quoteLiteral(path, false), literal, h.Quote(jsonKey))
+	return qm.Select(template), nil
+}
+
+func (h sqliteHelper) JsonValueCompare(jsonColumn string, jsonKey string, operator string, compareValue any) qm.QueryMod {
+	mod, _ := h.JsonValueCompareE(jsonColumn, jsonKey, operator, compareValue)
+	return mod
+}
+
+func (h sqliteHelper) JsonValueCompareE(jsonColumn string, jsonKey string, operator string, compareValue any) (qm.QueryMod, error) {
+	op, err := checkOperator(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := mysqlJsonPath(jsonKey) // sqlite和mysql的json路径语法一致
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, arg, err := parseJsonValue(compareValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return qm.Where(fmt.Sprintf("json_extract(%s, ?) %s ?", h.Quote(jsonColumn, true), op), path, arg), nil
+}
@@ -0,0 +1,237 @@
+package sqlboiler
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/aarondl/sqlboiler/v4/drivers"
+	"github.com/aarondl/sqlboiler/v4/queries"
+	"github.com/aarondl/sqlboiler/v4/queries/qm"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var sqliteDialect = drivers.Dialect{LQ: '"', RQ: '"'}
+
+// openSqlite 创建内存数据库并写入测试数据
+func openSqlite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每个连接都是独立的内存数据库
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	stmts := []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, nick TEXT, "we""ird" TEXT, profile TEXT NOT NULL)`,
+		`CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL, amount INTEGER NOT NULL)`,
+		`INSERT INTO users VALUES (1, 'alice', 'ali', 'x', '{"age":30,"city":"paris","vip":{"level":"gold"},"full name":"Alice A"}')`,
+		`INSERT INTO users VALUES (2, 'bob', NULL, 'y', '{"age":20,"city":"rome"}')`,
+		`INSERT INTO users VALUES (3, 'carol', NULL, 'z', '{"city":"o''hare"}')`,
+		`INSERT INTO orders VALUES (1, 1, 100), (2, 1, 50), (3, 2, 70)`,
+	}
+	for _, stmt := range stmts {
+		if _, err = db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return db
+}
+
+// querySqlite 执行QueryMods生成的查询, 返回所有行的第一列
+func querySqlite[T any](t *testing.T, db *sql.DB, mods ...qm.QueryMod) []T {
+	t.Helper()
+
+	q := &queries.Query{}
+	queries.SetDialect(q, &sqliteDialect)
+	qm.Apply(q, mods...)
+
+	rows, err := q.QueryContext(context.Background(), db)
+	if err != nil {
+		sqlStr, _ := queries.BuildQuery(q)
+		t.Fatalf("%s: %v", sqlStr, err)
+	}
+	defer rows.Close()
+
+	results := make([]T, 0)
+	for rows.Next() {
+		var v T
+		if err = rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, v)
+	}
+	if err = rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func assertEqual[T comparable](t *testing.T, got, want []T) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSqliteIfNull(t *testing.T) {
+	db := openSqlite(t)
+	h := Sqlite()
+
+	got := querySqlite[string](t, db,
+		qm.Select(h.IfNull("users.nick", "")),
+		qm.From(h.Quote("users")),
+		qm.OrderBy("id"),
+	)
+	assertEqual(t, got, []string{"ali", "", ""})
+
+	got = querySqlite[string](t, db,
+		qm.Select(h.IfNull("users.nick", "users.name", "display")),
+		qm.From(h.Quote("users")),
+		qm.OrderBy("id"),
+	)
+	assertEqual(t, got, []string{"ali", "bob", "carol"})
+
+	sums := querySqlite[int64](t, db,
+		qm.Select(h.SUM("orders.amount", "total")),
+		qm.From(h.Quote("orders")),
+		qm.Where("user_id=?", 3),
+	)
+	assertEqual(t, sums, []int64{0})
+}
+
+func TestSqliteJsonValue(t *testing.T) {
+	db := openSqlite(t)
+	h := Sqlite()
+
+	testCases := []struct {
+		name         string
+		jsonKey      string
+		defaultValue any
+		want         []string
+	}{
+		{name: "string", jsonKey: "city", defaultValue: "unknown", want: []string{"paris", "rome", "o'hare"}},
+		{name: "number", jsonKey: "age", defaultValue: 0, want: []string{"30", "20", "0"}},
+		{name: "nested", jsonKey: "vip.level", defaultValue: "none", want: []string{"gold", "none", "none"}},
+		{name: "key with space", jsonKey: "full name", defaultValue: "", want: []string{"Alice A", "", ""}},
+		{name: "escaped default", jsonKey: "missing", defaultValue: "it's", want: []string{"it's", "it's", "it's"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mod, err := h.JsonValueE("users.profile", tc.jsonKey, tc.defaultValue)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := querySqlite[string](t, db,
+				mod,
+				qm.From(h.Quote("users")),
+				qm.OrderBy("id"),
+			)
+			assertEqual(t, got, tc.want)
+		})
+	}
+
+	if h.JsonValue("users.profile", "a..b", 0) != nil {
+		t.Fatal("invalid json key should return nil")
+	}
+	if _, err := h.JsonValueE("users.profile", "age", []int{1}); err == nil {
+		t.Fatal("unsupported default value should return error")
+	}
+}
+
+func TestSqliteJsonValueCompare(t *testing.T) {
+	db := openSqlite(t)
+	h := Sqlite()
+
+	testCases := []struct {
+		name         string
+		jsonKey      string
+		operator     string
+		compareValue any
+		want         []int64
+	}{
+		{name: "number equal", jsonKey: "age", operator: "=", compareValue: 30, want: []int64{1}},
+		{name: "number greater", jsonKey: "age", operator: ">=", compareValue: 20, want: []int64{1, 2}},
+		{name: "float", jsonKey: "age", operator: "<", compareValue: 25.5, want: []int64{2}},
+		{name: "string", jsonKey: "city", operator: "<>", compareValue: "rome", want: []int64{1, 3}},
+		{name: "like", jsonKey: "city", operator: "like", compareValue: "%r%", want: []int64{1, 2, 3}},
+		{name: "not like", jsonKey: "city", operator: "NOT  LIKE", compareValue: "r%", want: []int64{1, 3}},
+		{name: "nested", jsonKey: "vip.level", operator: "=", compareValue: "gold", want: []int64{1}},
+		{name: "injection", jsonKey: "city", operator: "=", compareValue: "x' OR '1'='1", want: []int64{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mod, err := h.JsonValueCompareE("users.profile", tc.jsonKey, tc.operator, tc.compareValue)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := querySqlite[int64](t, db,
+				qm.Select("id"),
+				qm.From(h.Quote("users")),
+				mod,
+				qm.OrderBy("id"),
+			)
+			assertEqual(t, got, tc.want)
+		})
+	}
+
+	if _, err := h.JsonValueCompareE("users.profile", "age", "; DROP TABLE users", 1); err == nil {
+		t.Fatal("unsupported operator should return error")
+	}
+}
+
+func TestSqliteQuote(t *testing.T) {
+	db := openSqlite(t)
+	h := Sqlite()
+
+	got := querySqlite[string](t, db,
+		qm.Select(h.Quote(`users.we"ird`, true)),
+		qm.From(h.Quote("users")),
+		qm.OrderBy(h.Quote("users.id", true)),
+	)
+	assertEqual(t, got, []string{"x", "y", "z"})
+}
+
+func TestSqliteJoin(t *testing.T) {
+	db := openSqlite(t)
+	h := Sqlite()
+
+	got := querySqlite[string](t, db,
+		qm.Select(h.Quote("users.name", true)),
+		qm.From(h.Quote("users")),
+		h.InnerJoin("orders", "o").On("user_id", "users.id").And("o.amount > ?").Output(60),
+		qm.OrderBy(h.Quote("o.id", true)),
+	)
+	assertEqual(t, got, []string{"alice", "bob"})
+
+	counts := querySqlite[int64](t, db,
+		qm.Select("COUNT(orders.id)"),
+		qm.From(h.Quote("users")),
+		h.LeftJoin("orders").On("orders.user_id", "users.id").Output(),
+		qm.GroupBy("users.id"),
+		qm.OrderBy("users.id"),
+	)
+	assertEqual(t, counts, []int64{2, 1, 0})
+}
+
+func TestSqliteOrderBy(t *testing.T) {
+	db := openSqlite(t)
+	h := Sqlite()
+
+	got := querySqlite[int64](t, db,
+		qm.Select("id"),
+		qm.From(h.Quote("orders")),
+		h.OrderBy().Asc("orders.user_id").Desc("orders.amount").Output(),
+	)
+	assertEqual(t, got, []int64{1, 2, 3})
+}
@@ -1,36 +1,25 @@
 package sqlboiler
 
 import (
-	"fmt"
 	"github.com/aarondl/sqlboiler/v4/queries/qm"
 )
 
+// JsonValue 获取mysql Json字段中的值, 不支持的类型返回nil, 建议使用JsonValueE
 func JsonValue(jsonColumn string, jsonKey string, defaultValue any) qm.QueryMod {
-	var template string
-	switch v := defaultValue.(type) {
-	case string:
-		template = fmt.Sprintf("IFNULL(JSON_UNQUOTE(JSON_EXTRACT(%s, '$.%s')), '%s') AS %s", jsonColumn, jsonKey, v, jsonKey)
-	case int8, int, int32, int64:
-		template = fmt.Sprintf("IFNULL(JSON_EXTRACT(%s, '$.%s'), %d) AS %s", jsonColumn, jsonKey, v, jsonKey)
-	case float32, float64:
-		template = fmt.Sprintf("IFNULL(JSON_EXTRACT(%s, '$.%s'), %f) AS %s", jsonColumn, jsonKey, v, jsonKey)
-	default:
-		return nil
-	}
-	return qm.Select(template)
+	return Mysql().JsonValue(jsonColumn, jsonKey, defaultValue)
 }
 
+// JsonValueE 获取mysql Json字段中的值
+func JsonValueE(jsonColumn string, jsonKey string, defaultValue any) (qm.QueryMod, error) {
+	return Mysql().JsonValueE(jsonColumn, jsonKey, defaultValue)
+}
+
+// JsonValueCompare 比较mysql Json字段中的值, 不支持的类型或操作符返回nil, 建议使用JsonValueCompareE
 func JsonValueCompare(jsonColumn string, jsonKey string, operator string, compareValue any) qm.QueryMod {
-	var template string
-	switch v := compareValue.(type) {
-	case string:
-		template = fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '$.%s')) %s '%s'", jsonColumn, jsonKey, operator, v)
-	case int8, int, int32, int64:
-		template = fmt.Sprintf("JSON_EXTRACT(%s, '$.%s') %s %d", jsonColumn, jsonKey, operator, v)
-	case float32, float64:
-		template = fmt.Sprintf("JSON_EXTRACT(%s, '$.%s') %s %f", jsonColumn, jsonKey, operator, v)
-	default:
-		return nil
-	}
-	return qm.Where(template)
+	return Mysql().JsonValueCompare(jsonColumn, jsonKey, operator, compareValue)
+}
+
+// JsonValueCompareE 比较mysql Json字段中的值, 比较值以参数形式绑定
+func JsonValueCompareE(jsonColumn string, jsonKey string, operator string, compareValue any) (qm.QueryMod, error) {
+	return Mysql().JsonValueCompareE(jsonColumn, jsonKey, operator, compareValue)
 }
@@ -2,7 +2,12 @@ package sqlboiler
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aarondl/sqlboiler/v4/boil"
 	"github.com/hdget/common/biz"
@@ -11,59 +16,535 @@ import (
 )
 
 type Transactor interface {
-	Finalize(err error)
+	Finalize(err error)          // 结束事务, 错误只记录日志
+	FinalizeErr(err error) error // 结束事务并返回最终的错误
+	OnCommit(fn func())          // 最外层事务提交后执行
+	OnRollback(fn func())        // 最外层事务回滚后执行
+}
+
+type TransactorOption func(*transactorOptions)
+
+type transactorOptions struct {
+	savepoint bool                            // 嵌套事务时是否创建savepoint
+	isolation sql.IsolationLevel              // 事务隔离级别
+	readOnly  bool                            // 是否只读事务
+	timeout   time.Duration                   // 事务超时时间
+	database  string                          // 数据库名, 为空表示默认数据库
+	retries   int                             // RunInTx遇到可重试错误时的最大重试次数
+	backoff   func(attempt int) time.Duration // RunInTx重试前的等待时间
 }
 
 type trans struct {
-	tx     boil.Transactor
-	ctx    biz.Context
-	errLog func(msg string, kvs ...any)
+	group     *txGroup
+	session   *txSession
+	ctx       biz.Context
+	errLog    func(msg string, kvs ...any)
+	savepoint string // 嵌套事务创建的savepoint名字, 为空表示未创建
+	hookMark  int    // 创建savepoint时已注册的提交回调数量, 回滚到savepoint时丢弃之后注册的提交回调
+}
+
+// txGroup 保存在ctx中的事务组, 每个数据库各自有一个事务会话, 互不混用
+// txGroup实现了boil.ContextTransactor, 使用默认数据库的事务会话, 兼容通过ctx.Transactor().GetTx().(boil.Transactor)使用事务的代码
+type txGroup struct {
+	mu       sync.Mutex
+	sessions map[string]*txSession
+	adopted  boil.ContextTransactor // 接管的其他方式开启的默认数据库事务
+}
+
+// txSession 事务会话, 同一个数据库嵌套的Transactor共享同一个会话
+type txSession struct {
+	boil.ContextTransactor
+	database  string
+	refs      int // 引用计数, 由txGroup的锁保护
+	isolation sql.IsolationLevel
+	readOnly  bool
+	deadline  time.Time          // 事务的截止时间, 为零值表示没有截止时间
+	ctx       context.Context    // 开启事务的context, 接管的事务为nil
+	cancel    context.CancelFunc // 超时控制的cancel函数, 在最外层事务结束时调用
+
+	mu            sync.Mutex
+	commitHooks   []func() // 最外层事务提交后执行的回调
+	rollbackHooks []func() // 最外层事务回滚后执行的回调
+}
+
+var (
+	// savepointSeq 用于生成唯一的savepoint名字
+	savepointSeq atomic.Uint64
+	// adoptedGroups 接管的事务对应的事务组, ctx没有保存txGroup时通过它找到事务组
+	adoptedGroups sync.Map
+
+	errTransactorConflict = errors.New("transactor options conflict with outer transaction")
+	errNoTransaction      = errors.New("no transaction in context")
+	errNoContextExecutor  = errors.New("database does not support context-aware queries")
+)
+
+// WithSavepoint 嵌套事务时创建SAVEPOINT, 内层出错只回滚到该SAVEPOINT, 不会影响外层事务
+func WithSavepoint() TransactorOption {
+	return func(o *transactorOptions) {
+		o.savepoint = true
+	}
+}
+
+// WithIsolation 设置事务隔离级别, 加入外层事务时必须与外层事务一致
+func WithIsolation(level sql.IsolationLevel) TransactorOption {
+	return func(o *transactorOptions) {
+		o.isolation = level
+	}
+}
+
+// WithReadOnly 设置为只读事务, 外层为只读事务时内层也必须为只读事务, 只读事务不能加入外层的读写事务
+func WithReadOnly() TransactorOption {
+	return func(o *transactorOptions) {
+		o.readOnly = true
+	}
 }
 
-func NewTransactor(ctx biz.Context, logger types.LoggerProvider) (Transactor, error) {
+// WithTimeout 设置事务超时时间, 加入外层事务时外层事务必须在该时间内超时
+func WithTimeout(timeout time.Duration) TransactorOption {
+	return func(o *transactorOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithDatabase 指定在哪个已注册的数据库上开启事务
+func WithDatabase(name string) TransactorOption {
+	return func(o *transactorOptions) {
+		o.database = name
+	}
+}
+
+func NewTransactor(ctx biz.Context, logger types.LoggerProvider, options ...TransactorOption) (Transactor, error) {
+	return NewTransactorContext(context.Background(), ctx, logger, options...)
+}
+
+// NewTransactorContext 使用调用方的context开启事务, context取消或超时事务会被回滚
+func NewTransactorContext(c context.Context, ctx biz.Context, logger types.LoggerProvider, options ...TransactorOption) (Transactor, error) {
 	errLog := loggerUtils.Error
 	if logger != nil {
 		errLog = logger.Error
 	}
 
+	opts := &transactorOptions{}
+	for _, apply := range options {
+		apply(opts)
+	}
+
+	group := getTxGroup(ctx)
+
 	var err error
-	var transactor boil.Transactor
-	if v, ok := ctx.Transactor().GetTx().(boil.Transactor); ok {
-		transactor = v
+	var savepoint string
+	var hookMark int
+	defer func() {
+		// 开启失败时接管的事务组没有被引用, 不能残留在adoptedGroups中
+		if err != nil {
+			group.release()
+		}
+	}()
+
+	session := group.get(opts.database)
+	if session != nil {
+		if err = session.check(opts); err != nil {
+			return nil, err
+		}
+
+		// 嵌套事务创建savepoint, mysql和postgres语法一致
+		if opts.savepoint {
+			savepoint = fmt.Sprintf("sp_%d", savepointSeq.Add(1))
+			if _, err = session.ExecContext(c, "SAVEPOINT "+savepoint); err != nil {
+				return nil, err
+			}
+			hookMark = session.countCommitHooks()
+		}
 	} else { // 没找到，则new
-		transactor, err = boil.BeginTx(context.Background(), nil)
+		session, err = beginTxSession(c, opts)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	// ctx保存transaction
-	ctx.Transactor().Ref(transactor)
+	group.ref(session)
+	ctx.Transactor().Ref(group)
 
-	return &trans{tx: transactor, ctx: ctx, errLog: errLog}, nil
+	return &trans{group: group, session: session, ctx: ctx, errLog: errLog, savepoint: savepoint, hookMark: hookMark}, nil
 }
 
+// Finalize 结束事务, 提交或回滚的错误只记录日志
 func (t *trans) Finalize(err error) {
+	_ = t.FinalizeErr(err)
+}
+
+// FinalizeErr 结束事务并返回最终的错误, 回滚失败时返回原始错误与回滚错误的组合, 提交失败时返回提交错误
+// 这里的recover只处理结束事务时发生的panic, 调用方函数体中的panic需要先recover再以错误结束事务, 否则事务会被提交, e.g:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			_ = tx.FinalizeErr(fmt.Errorf("panic: %v", r))
+//			panic(r)
+//		}
+//		err = tx.FinalizeErr(err)
+//	}()
+//
+// 也可以直接使用RunInTx, 它已经按上面的方式处理了panic
+func (t *trans) FinalizeErr(err error) (result error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("panic: %v", r)
+			t.errLog("db finalize panic", "err", err, "panic", r)
+			result = errors.Join(err, fmt.Errorf("panic: %v", r))
 		}
 		t.ctx.Transactor().Unref()
 	}()
 
-	if needFinalize := t.ctx.Transactor().ReachRoot(); !needFinalize {
-		return
+	if needFinalize := t.group.unref(t.session); !needFinalize {
+		if t.savepoint != "" {
+			return t.finalizeSavepoint(err)
+		}
+		return err
 	}
 
+	defer t.session.close()
+
 	// need commit
 	if err != nil {
-		e := t.tx.Rollback()
+		e := t.session.Rollback()
 		t.errLog("db roll back", "err", err, "rollback", e)
-		return
+		t.runHooks(t.session.takeHooks(false))
+		if e != nil {
+			return errors.Join(err, fmt.Errorf("db roll back: %w", e))
+		}
+		return err
 	}
 
-	e := t.tx.Commit()
-	if e != nil {
+	if e := t.session.Commit(); e != nil {
+		// context取消或超时后事务已经被回滚, 提交只会返回sql.ErrTxDone, 加上context的错误说明原因
+		if ce := t.session.ctxErr(); ce != nil {
+			e = errors.Join(e, ce)
+		}
 		t.errLog("db commit", "err", e)
+		t.runHooks(t.session.takeHooks(false))
+		return fmt.Errorf("db commit: %w", e)
+	}
+
+	t.runHooks(t.session.takeHooks(true))
+	return nil
+}
+
+// OnCommit 注册最外层事务提交后执行的回调, 按注册顺序只执行一次
+// 如果在savepoint中注册且回滚到了该savepoint, 回调会被丢弃
+func (t *trans) OnCommit(fn func()) {
+	t.session.addHook(true, fn)
+}
+
+// OnRollback 注册最外层事务回滚后执行的回调, 按注册顺序只执行一次
+func (t *trans) OnRollback(fn func()) {
+	t.session.addHook(false, fn)
+}
+
+// runHooks 执行回调, 回调中的panic会被隔离并记录日志
+func (t *trans) runHooks(hooks []func()) {
+	for i, hook := range hooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.errLog("db transaction hook panic", "index", i, "panic", r)
+				}
+			}()
+			hook()
+		}()
+	}
+}
+
+// finalizeSavepoint 出错回滚到savepoint, 否则释放savepoint
+func (t *trans) finalizeSavepoint(err error) error {
+	if err != nil {
+		_, e := t.session.Exec("ROLLBACK TO SAVEPOINT " + t.savepoint)
+		t.errLog("db roll back to savepoint", "savepoint", t.savepoint, "err", err, "rollback", e)
+		t.session.discardCommitHooks(t.hookMark)
+		if e != nil {
+			return errors.Join(err, fmt.Errorf("db roll back to savepoint: %w", e))
+		}
+		return err
+	}
+
+	if _, e := t.session.Exec("RELEASE SAVEPOINT " + t.savepoint); e != nil {
+		t.errLog("db release savepoint", "savepoint", t.savepoint, "err", e)
+		return fmt.Errorf("db release savepoint: %w", e)
+	}
+	return nil
+}
+
+func beginTxSession(c context.Context, opts *transactorOptions) (*txSession, error) {
+	cluster, err := getCluster(opts.database)
+	if err != nil {
+		return nil, err
+	}
+
+	var cancel context.CancelFunc
+	if opts.timeout > 0 {
+		c, cancel = context.WithTimeout(c, opts.timeout)
+	}
+
+	tx, err := cluster.beginTx(c, &sql.TxOptions{Isolation: opts.isolation, ReadOnly: opts.readOnly})
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		return nil, err
+	}
+
+	deadline, _ := c.Deadline()
+	return &txSession{
+		ContextTransactor: tx,
+		database:          opts.database,
+		isolation:         opts.isolation,
+		readOnly:          opts.readOnly,
+		deadline:          deadline,
+		ctx:               c,
+		cancel:            cancel,
+	}, nil
+}
+
+// getTxGroup 获取ctx中的事务组, 没有则创建
+func getTxGroup(ctx biz.Context) *txGroup {
+	switch tx := ctx.Transactor().GetTx().(type) {
+	case *txGroup:
+		return tx
+	case boil.ContextTransactor:
+		return adoptTx(tx, true)
+	}
+	return &txGroup{sessions: make(map[string]*txSession)}
+}
+
+// currentTxSession 获取ctx中指定数据库正在进行的事务会话
+func currentTxSession(ctx biz.Context, database string) *txSession {
+	switch tx := ctx.Transactor().GetTx().(type) {
+	case *txGroup:
+		return tx.get(database)
+	case boil.ContextTransactor:
+		return adoptTx(tx, false).get(database)
+	}
+	return nil
+}
+
+// adoptTx 将ctx中其他方式开启的事务作为默认数据库的事务会话接管
+// 该事务由开启它的代码负责提交或回滚, 这里只会创建和释放savepoint, 所以注册的提交和回滚回调不会执行
+func adoptTx(tx boil.ContextTransactor, store bool) *txGroup {
+	if v, exist := adoptedGroups.Load(tx); exist {
+		return v.(*txGroup)
+	}
+
+	group := &txGroup{
+		sessions: map[string]*txSession{
+			DefaultDatabase: {ContextTransactor: tx, database: DefaultDatabase, refs: 1}, // 开启事务的代码持有一个引用, 引用计数不会归零
+		},
+		adopted: tx,
+	}
+	if !store {
+		return group
+	}
+
+	v, _ := adoptedGroups.LoadOrStore(tx, group)
+	return v.(*txGroup)
+}
+
+func (g *txGroup) get(database string) *txSession {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.sessions[database]
+}
+
+func (g *txGroup) ref(session *txSession) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	session.refs++
+	g.sessions[session.database] = session
+}
+
+// unref 减少会话的引用计数, 返回是否到达最外层事务, 到达时将会话从事务组中移除
+func (g *txGroup) unref(session *txSession) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	session.refs--
+	if session.refs > 0 {
+		g.releaseAdopted()
+		return false
+	}
+	delete(g.sessions, session.database)
+	g.releaseAdopted()
+	return true
+}
+
+// release 释放没有被引用的接管事务组
+func (g *txGroup) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.releaseAdopted()
+}
+
+// releaseAdopted 只剩下接管的事务且没有被Transactor引用时, 不再需要保存事务组
+func (g *txGroup) releaseAdopted() {
+	if g.adopted == nil || len(g.sessions) != 1 {
+		return
+	}
+	if session := g.sessions[DefaultDatabase]; session != nil && session.refs == 1 {
+		adoptedGroups.Delete(g.adopted)
+	}
+}
+
+// executor 返回默认数据库的事务会话, 默认数据库没有事务时返回默认数据库的主库
+func (g *txGroup) executor() boil.Executor {
+	if session := g.get(DefaultDatabase); session != nil {
+		return session
+	}
+
+	cluster, _ := getCluster(DefaultDatabase)
+	return cluster.Primary()
+}
+
+func (g *txGroup) Exec(query string, args ...any) (sql.Result, error) {
+	return g.executor().Exec(query, args...)
+}
+
+func (g *txGroup) Query(query string, args ...any) (*sql.Rows, error) {
+	return g.executor().Query(query, args...)
+}
+
+func (g *txGroup) QueryRow(query string, args ...any) *sql.Row {
+	return g.executor().QueryRow(query, args...)
+}
+
+func (g *txGroup) ExecContext(c context.Context, query string, args ...any) (sql.Result, error) {
+	exec, ok := g.executor().(boil.ContextExecutor)
+	if !ok {
+		return nil, errNoContextExecutor
+	}
+	return exec.ExecContext(c, query, args...)
+}
+
+func (g *txGroup) QueryContext(c context.Context, query string, args ...any) (*sql.Rows, error) {
+	exec, ok := g.executor().(boil.ContextExecutor)
+	if !ok {
+		return nil, errNoContextExecutor
+	}
+	return exec.QueryContext(c, query, args...)
+}
+
+func (g *txGroup) QueryRowContext(c context.Context, query string, args ...any) *sql.Row {
+	exec, ok := g.executor().(boil.ContextExecutor)
+	if !ok {
+		return g.executor().QueryRow(query, args...)
+	}
+	return exec.QueryRowContext(c, query, args...)
+}
+
+// Commit 提交默认数据库的事务, 一般应该通过Transactor.Finalize结束事务
+func (g *txGroup) Commit() error {
+	session := g.get(DefaultDatabase)
+	if session == nil {
+		return errNoTransaction
+	}
+	return session.Commit()
+}
+
+// Rollback 回滚默认数据库的事务, 一般应该通过Transactor.Finalize结束事务
+func (g *txGroup) Rollback() error {
+	session := g.get(DefaultDatabase)
+	if session == nil {
+		return errNoTransaction
+	}
+	return session.Rollback()
+}
+
+// check 检查加入外层事务时的选项是否与外层事务冲突
+func (s *txSession) check(opts *transactorOptions) error {
+	if opts.isolation != sql.LevelDefault && opts.isolation != s.isolation {
+		return fmt.Errorf("%w, isolation level: %s, outer: %s", errTransactorConflict, opts.isolation, s.isolation)
+	}
+
+	if s.readOnly != opts.readOnly {
+		if s.readOnly {
+			return fmt.Errorf("%w, read write in read only transaction", errTransactorConflict)
+		}
+		return fmt.Errorf("%w, read only in read write transaction", errTransactorConflict)
+	}
+
+	// 外层事务没有截止时间或者截止时间更晚, 内层的超时无法保证
+	if opts.timeout > 0 && (s.deadline.IsZero() || s.deadline.After(time.Now().Add(opts.timeout))) {
+		return fmt.Errorf("%w, timeout %s exceeds outer transaction deadline", errTransactorConflict, opts.timeout)
+	}
+	return nil
+}
+
+func (s *txSession) ctxErr() error {
+	if s.ctx == nil {
+		return nil
+	}
+	return s.ctx.Err()
+}
+
+func (s *txSession) close() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+func (s *txSession) addHook(commit bool, fn func()) {
+	if fn == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if commit {
+		s.commitHooks = append(s.commitHooks, fn)
+	} else {
+		s.rollbackHooks = append(s.rollbackHooks, fn)
+	}
+}
+
+// takeHooks 取出需要执行的回调并清空所有回调, 保证每个回调只执行一次
+func (s *txSession) takeHooks(committed bool) []func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hooks := s.rollbackHooks
+	if committed {
+		hooks = s.commitHooks
+	}
+	s.commitHooks, s.rollbackHooks = nil, nil
+	return hooks
+}
+
+func (s *txSession) countCommitHooks() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.commitHooks)
+}
+
+// discardCommitHooks 丢弃从mark开始注册的提交回调
+func (s *txSession) discardCommitHooks(mark int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if mark < len(s.commitHooks) {
+		s.commitHooks = s.commitHooks[:mark]
+	}
+}
+
+// OnCommit 注册ctx中当前事务提交后执行的回调, 可以指定数据库名, ctx中没有事务时返回错误
+func OnCommit(ctx biz.Context, fn func(), database ...string) error {
+	session := currentTxSession(ctx, getDatabase(database...))
+	if session == nil {
+		return errNoTransaction
+	}
+	session.addHook(true, fn)
+	return nil
+}
+
+// OnRollback 注册ctx中当前事务回滚后执行的回调, 可以指定数据库名, ctx中没有事务时返回错误
+func OnRollback(ctx biz.Context, fn func(), database ...string) error {
+	session := currentTxSession(ctx, getDatabase(database...))
+	if session == nil {
+		return errNoTransaction
 	}
+	session.addHook(false, fn)
+	return nil
 }
@@ -0,0 +1,163 @@
+package sqlboiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hdget/common/biz"
+)
+
+const (
+	defaultTxRetries    = 3
+	defaultTxBackoff    = 10 * time.Millisecond
+	defaultTxMaxBackoff = time.Second
+)
+
+var (
+	// mysql可重试的错误码, 1213: 死锁, 1205: 锁等待超时
+	mysqlRetryableErrors = map[uint16]struct{}{
+		1213: {},
+		1205: {},
+	}
+
+	// postgres可重试的SQLSTATE, 40001: 序列化失败, 40P01: 死锁
+	psqlRetryableErrors = map[string]struct{}{
+		"40001": {},
+		"40P01": {},
+	}
+)
+
+// WithRetry 设置RunInTx遇到死锁或序列化失败等错误时的最大重试次数, 0表示不重试
+func WithRetry(maxRetries int) TransactorOption {
+	return func(o *transactorOptions) {
+		o.retries = maxRetries
+	}
+}
+
+// WithBackoff 设置RunInTx重试前的等待时间, attempt从1开始
+func WithBackoff(backoff func(attempt int) time.Duration) TransactorOption {
+	return func(o *transactorOptions) {
+		o.backoff = backoff
+	}
+}
+
+// RunInTx 在事务中执行fn, fn返回错误或panic时回滚, 否则提交, e.g:
+//
+//	err := RunInTx(ctx, func(db Db) error {
+//		return user.Insert(db.Executor(), boil.Infer())
+//	})
+//
+// 只有在开启最外层事务时才会对可重试的错误进行重试, 嵌套在外层事务中时由外层事务负责重试
+func RunInTx(ctx biz.Context, fn func(Db) error, options ...TransactorOption) error {
+	return RunInTxContext(context.Background(), ctx, fn, options...)
+}
+
+// RunInTxContext 使用调用方的context在事务中执行fn, context取消时停止重试
+func RunInTxContext(c context.Context, ctx biz.Context, fn func(Db) error, options ...TransactorOption) error {
+	opts := &transactorOptions{retries: defaultTxRetries, backoff: defaultBackoff}
+	for _, apply := range options {
+		apply(opts)
+	}
+	if opts.backoff == nil {
+		opts.backoff = defaultBackoff
+	}
+
+	// 已经在事务中, 不重试
+	if currentTxSession(ctx, opts.database) != nil {
+		return runInTx(c, ctx, opts.database, fn, options...)
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = runInTx(c, ctx, opts.database, fn, options...)
+		if err == nil || attempt >= opts.retries || !IsRetryableTxError(err) {
+			return err
+		}
+
+		select {
+		case <-c.Done():
+			return errors.Join(err, c.Err())
+		case <-time.After(opts.backoff(attempt + 1)):
+		}
+	}
+}
+
+// IsRetryableTxError 判断是否为可重试的事务错误, 包括mysql的死锁, 锁等待超时以及postgres的序列化失败和死锁
+func IsRetryableTxError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if e, ok := err.(interface{ SQLState() string }); ok { // lib/pq, pgx
+		if _, exist := psqlRetryableErrors[e.SQLState()]; exist {
+			return true
+		}
+	}
+
+	if number, ok := mysqlErrorNumber(err); ok { // go-sql-driver/mysql
+		if _, exist := mysqlRetryableErrors[number]; exist {
+			return true
+		}
+	}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return IsRetryableTxError(e.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, inner := range e.Unwrap() {
+			if IsRetryableTxError(inner) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func runInTx(c context.Context, ctx biz.Context, database string, fn func(Db) error, options ...TransactorOption) (err error) {
+	tx, err := NewTransactorContext(c, ctx, nil, options...)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.FinalizeErr(fmt.Errorf("panic: %v", r))
+			panic(r)
+		}
+		err = tx.FinalizeErr(err)
+	}()
+
+	return fn(NewGdb(ctx, database))
+}
+
+// mysqlErrorNumber 获取mysql驱动错误的错误码, 为了不依赖驱动包, 通过反射读取MySQLError.Number
+func mysqlErrorNumber(err error) (uint16, bool) {
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return 0, false
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct || v.Type().Name() != "MySQLError" {
+		return 0, false
+	}
+
+	number := v.FieldByName("Number")
+	if !number.IsValid() || number.Kind() != reflect.Uint16 {
+		return 0, false
+	}
+	return uint16(number.Uint()), true
+}
+
+func defaultBackoff(attempt int) time.Duration {
+	backoff := defaultTxBackoff << attempt
+	if backoff <= 0 || backoff > defaultTxMaxBackoff {
+		return defaultTxMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,200 @@
+package sqlboiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// MySQLError 模拟go-sql-driver/mysql的错误类型, IsRetryableTxError通过类型名和Number字段识别
+type MySQLError struct {
+	Number  uint16
+	Message string
+}
+
+func (e MySQLError) Error() string {
+	return fmt.Sprintf("Error %d: %s", e.Number, e.Message)
+}
+
+// pqError 模拟lib/pq和pgx的错误类型
+type pqError struct {
+	code string
+}
+
+func (e pqError) Error() string {
+	return "pq: " + e.code
+}
+
+func (e pqError) SQLState() string {
+	return e.code
+}
+
+var errDeadlock = &MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+
+func TestIsRetryableTxError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil},
+		{name: "plain", err: errors.New("deadlock")},
+		{name: "mysql deadlock", err: errDeadlock, want: true},
+		{name: "mysql lock wait timeout", err: &MySQLError{Number: 1205}, want: true},
+		{name: "mysql value", err: MySQLError{Number: 1213}, want: true},
+		{name: "mysql duplicate", err: &MySQLError{Number: 1062}},
+		{name: "psql serialization", err: pqError{code: "40001"}, want: true},
+		{name: "psql deadlock", err: pqError{code: "40P01"}, want: true},
+		{name: "psql unique violation", err: pqError{code: "23505"}},
+		{name: "fmt wrapped", err: fmt.Errorf("insert user: %w", errDeadlock), want: true},
+		{name: "pkg wrapped", err: pkgerrors.Wrap(pqError{code: "40001"}, "update order"), want: true},
+		{name: "joined", err: errors.Join(errors.New("rollback"), fmt.Errorf("commit: %w", pqError{code: "40P01"})), want: true},
+		{name: "joined not retryable", err: errors.Join(errors.New("rollback"), &MySQLError{Number: 1062})},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsRetryableTxError(tc.err); got != tc.want {
+				t.Fatalf("IsRetryableTxError(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunInTxRetry(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	// 可重试的错误按backoff重试, 每次都在新的事务中执行
+	attempts := 0
+	backoffs := make([]int, 0)
+	err := RunInTx(ctx, func(tdb Db) error {
+		attempts++
+		if _, err := tdb.Executor().Exec("INSERT INTO items (name) VALUES (?)", fmt.Sprintf("attempt %d", attempts)); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return fmt.Errorf("insert item: %w", errDeadlock)
+		}
+		return nil
+	}, WithDatabase(database), WithBackoff(func(attempt int) time.Duration {
+		backoffs = append(backoffs, attempt)
+		return time.Millisecond
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 || !slices.Equal(backoffs, []int{1, 2}) {
+		t.Fatalf("unexpected attempts: %d, backoffs: %v", attempts, backoffs)
+	}
+	if got := itemNames(t, db); !slices.Equal(got, []string{"attempt 3"}) {
+		t.Fatalf("unexpected items: %v", got)
+	}
+
+	// 超过重试次数返回最后的错误
+	attempts = 0
+	err = RunInTx(ctx, func(Db) error {
+		attempts++
+		return errDeadlock
+	}, WithDatabase(database), WithRetry(1), WithBackoff(func(int) time.Duration { return 0 }))
+	if !errors.Is(err, errDeadlock) || attempts != 2 {
+		t.Fatalf("expect 2 attempts and deadlock error, got: %d, %v", attempts, err)
+	}
+
+	// 不可重试的错误不重试
+	attempts = 0
+	errBoom := errors.New("boom")
+	err = RunInTx(ctx, func(Db) error {
+		attempts++
+		return errBoom
+	}, WithDatabase(database))
+	if !errors.Is(err, errBoom) || attempts != 1 {
+		t.Fatalf("expect 1 attempt and original error, got: %d, %v", attempts, err)
+	}
+}
+
+func TestRunInTxNested(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	outer, err := NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 嵌套在外层事务中时不重试, 由外层事务负责
+	attempts := 0
+	err = RunInTx(ctx, func(tdb Db) error {
+		attempts++
+		if _, err := tdb.Executor().Exec("INSERT INTO items (name) VALUES ('nested')"); err != nil {
+			return err
+		}
+		return errDeadlock
+	}, WithDatabase(database))
+	if !errors.Is(err, errDeadlock) || attempts != 1 {
+		t.Fatalf("expect 1 attempt and deadlock error, got: %d, %v", attempts, err)
+	}
+
+	if err = outer.FinalizeErr(err); !errors.Is(err, errDeadlock) {
+		t.Fatal(err)
+	}
+	if got := itemNames(t, db); len(got) != 0 {
+		t.Fatalf("unexpected items: %v", got)
+	}
+}
+
+func TestRunInTxPanic(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("expect panic to be re-raised, got: %v", r)
+			}
+		}()
+		_ = RunInTx(ctx, func(tdb Db) error {
+			if _, err := tdb.Executor().Exec("INSERT INTO items (name) VALUES ('panic')"); err != nil {
+				return err
+			}
+			panic("boom")
+		}, WithDatabase(database))
+	}()
+
+	if got := itemNames(t, db); len(got) != 0 {
+		t.Fatalf("expect rollback on panic, items: %v", got)
+	}
+	if ctx.transactor.tx != nil {
+		t.Fatal("transaction left in context after panic")
+	}
+}
+
+func TestRunInTxContextCanceled(t *testing.T) {
+	database, _ := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	// 等待重试时context取消, 返回最后的错误和context的错误
+	c, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := RunInTxContext(c, ctx, func(Db) error {
+		attempts++
+		cancel()
+		return errDeadlock
+	}, WithDatabase(database), WithBackoff(func(int) time.Duration { return time.Hour }))
+	if !errors.Is(err, errDeadlock) || !errors.Is(err, context.Canceled) || attempts != 1 {
+		t.Fatalf("expect 1 attempt with deadlock and canceled errors, got: %d, %v", attempts, err)
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	if got := defaultBackoff(1); got != 2*defaultTxBackoff {
+		t.Fatalf("unexpected backoff: %v", got)
+	}
+	if got := defaultBackoff(100); got != defaultTxMaxBackoff {
+		t.Fatalf("backoff not capped: %v", got)
+	}
+}
@@ -0,0 +1,565 @@
+package sqlboiler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hdget/common/biz"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// testTransactor 模拟biz.Transactor, 保存最后一次Ref的事务, 引用计数归零时清除
+type testTransactor struct {
+	biz.Transactor
+	tx   any
+	refs int
+}
+
+func (t *testTransactor) GetTx() any {
+	return t.tx
+}
+
+func (t *testTransactor) Ref(tx any) {
+	t.tx = tx
+	t.refs++
+}
+
+func (t *testTransactor) Unref() {
+	t.refs--
+	if t.refs == 0 {
+		t.tx = nil
+	}
+}
+
+// testContext 模拟biz.Context, 只实现事务和租户相关的方法
+type testContext struct {
+	biz.Context
+	tid        int64
+	transactor *testTransactor
+}
+
+func newTestContext(tid int64) *testContext {
+	return &testContext{tid: tid, transactor: &testTransactor{}}
+}
+
+func (c *testContext) Tid() int64 {
+	return c.tid
+}
+
+func (c *testContext) Transactor() biz.Transactor {
+	return c.transactor
+}
+
+// openTxDatabase 创建sqlite文件数据库并以测试名注册, 返回数据库名
+// items.parent_id是延迟检查的外键, 插入不存在的parent_id会在提交时失败
+func openTxDatabase(t *testing.T) (string, *sql.DB) {
+	t.Helper()
+	return t.Name(), openNamedTxDatabase(t, t.Name())
+}
+
+// openNamedTxDatabase 创建sqlite文件数据库并以指定的名字注册
+func openNamedTxDatabase(t *testing.T, name string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "tx.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 单个连接, 事务结束前不能在事务外查询
+	db.SetMaxOpenConns(1)
+
+	stmts := []string{
+		`CREATE TABLE parents (id INTEGER PRIMARY KEY)`,
+		`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL, parent_id INTEGER REFERENCES parents(id) DEFERRABLE INITIALLY DEFERRED)`,
+	}
+	for _, stmt := range stmts {
+		if _, err = db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	RegisterDatabase(name, NewCluster(db))
+	t.Cleanup(func() {
+		clusters.Delete(name)
+		_ = db.Close()
+	})
+	return db
+}
+
+func insertItem(t *testing.T, ctx biz.Context, database, name string) {
+	t.Helper()
+	if _, err := NewGdb(ctx, database).Executor().Exec("INSERT INTO items (name) VALUES (?)", name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// itemNames 返回已提交的items, 按id排序
+func itemNames(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT name FROM items ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestFinalizeErr(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+	errBoom := errors.New("boom")
+
+	tx, err := NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, database, "committed")
+	if err = tx.FinalizeErr(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err = NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, database, "rolled back")
+	if err = tx.FinalizeErr(errBoom); err != errBoom {
+		t.Fatalf("expect original error, got: %v", err)
+	}
+
+	if got := itemNames(t, db); !slices.Equal(got, []string{"committed"}) {
+		t.Fatalf("unexpected items: %v", got)
+	}
+	if ctx.transactor.tx != nil || ctx.transactor.refs != 0 {
+		t.Fatalf("transaction left in context: %+v", ctx.transactor)
+	}
+}
+
+func TestFinalizeErrCommitFailure(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	tx, err := NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var committed, rolledBack bool
+	tx.OnCommit(func() { committed = true })
+	tx.OnRollback(func() { rolledBack = true })
+
+	if _, err = NewGdb(ctx, database).Executor().Exec("INSERT INTO items (name, parent_id) VALUES ('orphan', 99)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 外键在提交时检查失败
+	err = tx.FinalizeErr(nil)
+	if err == nil || !strings.Contains(err.Error(), "FOREIGN KEY") || committed || !rolledBack {
+		t.Fatalf("expect commit error and rollback hooks, got: %v, committed: %v, rolled back: %v", err, committed, rolledBack)
+	}
+	if got := itemNames(t, db); len(got) != 0 {
+		t.Fatalf("unexpected items: %v", got)
+	}
+}
+
+func TestFinalizeErrRollbackFailure(t *testing.T) {
+	database, _ := openTxDatabase(t)
+	ctx := newTestContext(1)
+	errBoom := errors.New("boom")
+
+	tx, err := NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 事务已经被其他代码结束, 再次回滚失败
+	if err = ctx.Transactor().GetTx().(*txGroup).get(database).Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.FinalizeErr(errBoom)
+	if !errors.Is(err, errBoom) || !errors.Is(err, sql.ErrTxDone) {
+		t.Fatalf("expect original and rollback errors, got: %v", err)
+	}
+}
+
+func TestFinalizeErrPanic(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	var rolledBack bool
+	run := func() (err error) {
+		tx, err := NewTransactor(ctx, nil, WithDatabase(database))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				_ = tx.FinalizeErr(fmt.Errorf("panic: %v", r))
+				panic(r)
+			}
+			err = tx.FinalizeErr(err)
+		}()
+
+		tx.OnRollback(func() { rolledBack = true })
+		insertItem(t, ctx, database, "before panic")
+		panic("boom")
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("expect panic to be re-raised, got: %v", r)
+			}
+		}()
+		_ = run()
+	}()
+
+	if got := itemNames(t, db); len(got) != 0 || !rolledBack {
+		t.Fatalf("expect rollback on panic, items: %v, rolled back: %v", got, rolledBack)
+	}
+}
+
+func TestSavepoint(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	outer, err := NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hooks := make([]string, 0)
+	outer.OnCommit(func() { hooks = append(hooks, "outer") })
+	insertItem(t, ctx, database, "outer")
+
+	// 内层出错只回滚到savepoint, 之后注册的提交回调被丢弃
+	inner, err := NewTransactor(ctx, nil, WithDatabase(database), WithSavepoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner.OnCommit(func() { hooks = append(hooks, "rolled back inner") })
+	insertItem(t, ctx, database, "rolled back inner")
+	errInner := errors.New("inner failed")
+	if err = inner.FinalizeErr(errInner); err != errInner {
+		t.Fatalf("expect inner error, got: %v", err)
+	}
+
+	// 内层成功释放savepoint, 写入和提交回调保留到外层提交
+	inner, err = NewTransactor(ctx, nil, WithDatabase(database), WithSavepoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner.OnCommit(func() { hooks = append(hooks, "released inner") })
+	insertItem(t, ctx, database, "released inner")
+	if err = inner.FinalizeErr(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = outer.FinalizeErr(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := itemNames(t, db); !slices.Equal(got, []string{"outer", "released inner"}) {
+		t.Fatalf("unexpected items: %v", got)
+	}
+	if !slices.Equal(hooks, []string{"outer", "released inner"}) {
+		t.Fatalf("unexpected commit hooks: %v", hooks)
+	}
+}
+
+func TestSavepointOuterRollback(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	outer, err := NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := NewTransactor(ctx, nil, WithDatabase(database), WithSavepoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var committed bool
+	inner.OnCommit(func() { committed = true })
+	insertItem(t, ctx, database, "inner")
+	if err = inner.FinalizeErr(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 释放的savepoint仍然随外层事务回滚
+	if err = outer.FinalizeErr(errors.New("outer failed")); err == nil {
+		t.Fatal("expect outer error")
+	}
+	if got := itemNames(t, db); len(got) != 0 || committed {
+		t.Fatalf("expect outer rollback, items: %v, committed: %v", got, committed)
+	}
+}
+
+func TestTransactorConflict(t *testing.T) {
+	database, _ := openTxDatabase(t)
+
+	testCases := []struct {
+		name     string
+		outer    []TransactorOption
+		inner    []TransactorOption
+		conflict bool
+	}{
+		{name: "same options", inner: nil},
+		{name: "isolation", outer: []TransactorOption{WithIsolation(sql.LevelSerializable)}, inner: []TransactorOption{WithIsolation(sql.LevelReadCommitted)}, conflict: true},
+		{name: "default isolation", outer: []TransactorOption{WithIsolation(sql.LevelSerializable)}, inner: []TransactorOption{WithIsolation(sql.LevelDefault)}},
+		{name: "read only in read write", inner: []TransactorOption{WithReadOnly()}, conflict: true},
+		{name: "read write in read only", outer: []TransactorOption{WithReadOnly()}, conflict: true},
+		{name: "read only in read only", outer: []TransactorOption{WithReadOnly()}, inner: []TransactorOption{WithReadOnly()}},
+		{name: "timeout without outer deadline", inner: []TransactorOption{WithTimeout(time.Minute)}, conflict: true},
+		{name: "timeout before outer deadline", outer: []TransactorOption{WithTimeout(time.Hour)}, inner: []TransactorOption{WithTimeout(time.Minute)}, conflict: true},
+		{name: "timeout after outer deadline", outer: []TransactorOption{WithTimeout(time.Minute)}, inner: []TransactorOption{WithTimeout(time.Hour)}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newTestContext(1)
+			outer, err := NewTransactor(ctx, nil, append(tc.outer, WithDatabase(database))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer outer.Finalize(nil)
+
+			inner, err := NewTransactor(ctx, nil, append(tc.inner, WithDatabase(database))...)
+			if tc.conflict {
+				if !errors.Is(err, errTransactorConflict) {
+					t.Fatalf("expect conflict, got: %v", err)
+				}
+				if ctx.transactor.refs != 1 {
+					t.Fatalf("conflicting transactor referenced: %d", ctx.transactor.refs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			inner.Finalize(nil)
+		})
+	}
+}
+
+func TestTransactorContext(t *testing.T) {
+	database, db := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	// context已取消时无法开启事务
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewTransactorContext(c, ctx, nil, WithDatabase(database)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect context canceled, got: %v", err)
+	}
+	if ctx.transactor.tx != nil {
+		t.Fatal("failed transactor left in context")
+	}
+
+	// 事务过程中context取消, 事务被回滚, 提交返回context的错误
+	c, cancel = context.WithCancel(context.Background())
+	tx, err := NewTransactorContext(c, ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, database, "canceled")
+	cancel()
+	if err = tx.FinalizeErr(nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect context canceled, got: %v", err)
+	}
+
+	// 事务超时
+	tx, err = NewTransactor(ctx, nil, WithDatabase(database), WithTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, database, "timeout")
+	time.Sleep(50 * time.Millisecond)
+	if err = tx.FinalizeErr(nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got: %v", err)
+	}
+
+	if got := itemNames(t, db); len(got) != 0 {
+		t.Fatalf("unexpected items: %v", got)
+	}
+}
+
+func TestTransactorHooks(t *testing.T) {
+	database, _ := openTxDatabase(t)
+	ctx := newTestContext(1)
+
+	if err := OnCommit(ctx, func() {}, database); !errors.Is(err, errNoTransaction) {
+		t.Fatalf("expect no transaction error, got: %v", err)
+	}
+
+	outer, err := NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := make([]string, 0)
+	outer.OnCommit(func() { calls = append(calls, "outer commit") })
+	outer.OnRollback(func() { calls = append(calls, "outer rollback") })
+
+	// 内层事务和包级函数注册的回调都在最外层事务结束时按注册顺序执行
+	inner, err := NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner.OnCommit(func() { panic("hook failed") })
+	inner.OnCommit(func() { calls = append(calls, "inner commit") })
+	if err = OnCommit(ctx, func() { calls = append(calls, "ctx commit") }, database); err != nil {
+		t.Fatal(err)
+	}
+	if err = inner.FinalizeErr(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("hooks run before outer transaction finished: %v", calls)
+	}
+
+	// panic的回调不影响其他回调和事务结果
+	if err = outer.FinalizeErr(nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"outer commit", "inner commit", "ctx commit"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("unexpected hooks: %v, want: %v", calls, want)
+	}
+
+	// 回调只执行一次, 不会被下一个事务再次执行
+	calls = calls[:0]
+	tx, err := NewTransactor(ctx, nil, WithDatabase(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.OnRollback(func() { calls = append(calls, "rollback") })
+	if err = tx.FinalizeErr(errors.New("boom")); err == nil {
+		t.Fatal("expect error")
+	}
+	if !slices.Equal(calls, []string{"rollback"}) {
+		t.Fatalf("unexpected hooks: %v", calls)
+	}
+}
+
+func TestTransactorDatabases(t *testing.T) {
+	orders := t.Name() + "/orders"
+	users := t.Name() + "/users"
+	ordersDb := openNamedTxDatabase(t, orders)
+	usersDb := openNamedTxDatabase(t, users)
+	ctx := newTestContext(1)
+
+	// 每个数据库有独立的事务会话, 可以分别提交或回滚
+	ordersTx, err := NewTransactor(ctx, nil, WithDatabase(orders))
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertItem(t, ctx, orders, "order")
+	if _, ok := NewGdb(ctx, users).Executor().(*txSession); ok {
+		t.Fatal("users joined the orders transaction")
+	}
+
+	usersTx, err := NewTransactor(ctx, nil, WithDatabase(users), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session, ok := NewGdb(ctx, users).Executor().(*txSession); !ok || session == ctx.transactor.tx.(*txGroup).get(orders) {
+		t.Fatal("users transaction not in context")
+	}
+	if err = usersTx.FinalizeErr(errors.New("boom")); err == nil {
+		t.Fatal("expect error")
+	}
+
+	// 一个数据库的事务结束不影响其他数据库的事务
+	if _, ok := NewGdb(ctx, orders).Executor().(*txSession); !ok {
+		t.Fatal("orders transaction ended with users transaction")
+	}
+	insertItem(t, ctx, orders, "order 2")
+	if err = ordersTx.FinalizeErr(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := itemNames(t, ordersDb); !slices.Equal(got, []string{"order", "order 2"}) {
+		t.Fatalf("unexpected orders: %v", got)
+	}
+	if got := itemNames(t, usersDb); len(got) != 0 {
+		t.Fatalf("unexpected users: %v", got)
+	}
+	if ctx.transactor.tx != nil {
+		t.Fatal("transaction left in context")
+	}
+}
+
+func TestTransactorAdopted(t *testing.T) {
+	_, db := openTxDatabase(t)
+
+	testCases := []struct {
+		name    string
+		options []TransactorOption
+		commit  bool // 开启Transactor前结束接管的事务, 使SAVEPOINT失败
+	}{
+		{name: "conflict", options: []TransactorOption{WithReadOnly()}},
+		{name: "savepoint", options: []TransactorOption{WithSavepoint()}, commit: true},
+		{name: "unregistered database", options: []TransactorOption{WithDatabase("missing")}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = tx.Rollback() }()
+			if tc.commit {
+				if err = tx.Commit(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// 开启失败时不会残留接管的事务组
+			ctx := newTestContext(1)
+			ctx.transactor.tx = tx
+			if _, err = NewTransactor(ctx, nil, tc.options...); err == nil {
+				t.Fatal("expect error")
+			}
+			if _, exist := adoptedGroups.Load(tx); exist {
+				t.Fatal("adopted group leaked")
+			}
+		})
+	}
+
+	// 接管的事务在Transactor结束后释放, 由开启它的代码提交
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := newTestContext(1)
+	ctx.transactor.tx = tx
+	inner, err := NewTransactor(ctx, nil, WithSavepoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exist := adoptedGroups.Load(tx); !exist {
+		t.Fatal("adopted group not stored")
+	}
+	if err = inner.FinalizeErr(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, exist := adoptedGroups.Load(tx); exist {
+		t.Fatal("adopted group not released")
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -14,13 +14,13 @@ func escape(s, quote string, splitWord ...bool) string {
 				builder.WriteString(".") // 直接写入点号+双引号组合
 			}
 			builder.WriteString(quote)
-			builder.WriteString(p)
+			builder.WriteString(strings.ReplaceAll(p, quote, quote+quote)) // 标识符中的引号需要双写转义
 			builder.WriteString(quote)
 		}
 	} else {
 		builder.Grow(len(s) + 2)
 		builder.WriteString(quote)
-		builder.WriteString(s)
+		builder.WriteString(strings.ReplaceAll(s, quote, quote+quote))
 		builder.WriteString(quote)
 	}
 	return builder.String()